@@ -0,0 +1,160 @@
+//Package admin opt-in admin http server for operations,使用单独的端口
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/dao"
+	"github.com/tonyjt/tgo_v2/health"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/metrics"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+)
+
+//build info,编译时设置 -ldflags "-X github.com/tonyjt/tgo_v2/admin.Version=1.0.0"
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+var (
+	server    *http.Server
+	serverMux sync.Mutex
+	startTime = time.Now()
+)
+
+//Start start admin server on config Address,不阻塞
+func Start() error {
+	serverMux.Lock()
+	defer serverMux.Unlock()
+
+	if server != nil {
+		return nil
+	}
+
+	conf := config.AdminGet()
+
+	listener, err := net.Listen("tcp", conf.Address)
+
+	if err != nil {
+		log.Errorf("admin server listen %s failed:%s", conf.Address, err.Error())
+		return err
+	}
+
+	server = &http.Server{Handler: Engine()}
+
+	go func(s *http.Server) {
+		if err := s.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("admin server stopped:%s", err.Error())
+		}
+	}(server)
+
+	return nil
+}
+
+//Stop stop admin server
+func Stop(ctx context.Context) error {
+	serverMux.Lock()
+	defer serverMux.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	err := server.Shutdown(ctx)
+	server = nil
+
+	return err
+}
+
+//Engine gin engine of admin endpoints,可以自己挂到其他server上
+func Engine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery(), auth())
+
+	debug := engine.Group("/debug/pprof")
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		debug.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+
+	engine.GET("/config", handlerConfig)
+	engine.GET("/features", handlerFeatures)
+	engine.GET("/log/level", handlerLogLevel)
+	engine.PUT("/log/level", handlerLogLevelSet)
+	engine.GET("/stats", dao.StatsHandler())
+	engine.GET("/health/ready", health.HandlerReadiness())
+	engine.GET("/health/live", health.HandlerLiveness())
+	engine.GET("/build", handlerBuild)
+
+	if config.FeatureMetrics() {
+		engine.GET("/metrics", metrics.Handler())
+	}
+
+	return engine
+}
+
+//auth check bearer token when config Token is not empty
+func auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.AdminGet().Token
+
+		if token == "" {
+			c.Next()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+func handlerConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, config.Dump())
+}
+
+func handlerFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, config.FeatureGet())
+}
+
+func handlerLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": log.GetLevel().String()})
+}
+
+//handlerLogLevelSet PUT /log/level?level=debug
+func handlerLogLevelSet(c *gin.Context) {
+	level, err := log.ParseLevel(c.Query("level"))
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.SetLevel(level)
+
+	c.JSON(http.StatusOK, gin.H{"level": level.String()})
+}
+
+func handlerBuild(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":    Version,
+		"commit":     Commit,
+		"build_time": BuildTime,
+		"go_version": runtime.Version(),
+		"start_time": startTime.Unix(),
+		"uptime":     int64(time.Since(startTime) / time.Second),
+	})
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"github.com/tonyjt/tgo_v2/log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	engine := Engine()
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/log/level?level=debug", nil)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || log.GetLevel() != log.LevelDebug {
+		t.Errorf("set level status:%d,level:%s", w.Code, log.GetLevel())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPut, "/log/level?level=unknown", nil)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid level status:%d", w.Code)
+	}
+}
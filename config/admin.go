@@ -0,0 +1,31 @@
+package config
+
+type Admin struct {
+	Address string //admin server地址,如127.0.0.1:9090
+	Token   string //不为空时请求需要带Authorization: Bearer <Token>
+}
+
+var (
+	adminConfig *Admin
+)
+
+func init() {
+	adminConfig = &Admin{}
+
+	err := configGet("admin", adminConfig, false, nil)
+
+	if err != nil || adminConfig.Address == "" {
+		adminConfig.Address = configAdminGetDefault().Address
+	}
+}
+
+func configAdminGetDefault() *Admin {
+	return &Admin{Address: "127.0.0.1:9090"}
+}
+
+func AdminGet() *Admin {
+	if adminConfig == nil {
+		panic("admin config is nil")
+	}
+	return adminConfig
+}
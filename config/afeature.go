@@ -2,13 +2,14 @@ package config
 
 //Feature feature struct
 type Feature struct {
-	Zipkin bool
-	Mongo  bool
-	Mysql  bool
-	Redis  bool
-	Grpc   bool
-	HTTP   bool
-	Es     bool
+	Zipkin  bool
+	Mongo   bool
+	Mysql   bool
+	Redis   bool
+	Grpc    bool
+	HTTP    bool
+	Es      bool
+	Metrics bool
 }
 
 var (
@@ -74,3 +75,8 @@ func FeatureHTTP() bool {
 func FeatureEs() bool {
 	return featureConfig.Es
 }
+
+//FeatureMetrics get metrics feature
+func FeatureMetrics() bool {
+	return featureConfig.Metrics
+}
@@ -9,6 +9,9 @@ import (
 	"sync"
 )
 
+//configOptional 可以没有配置文件的config,使用默认配置
+var configOptional = map[string]bool{"resp": true, "health": true, "admin": true}
+
 func Get(name string,data interface{}, sync bool, mutex *sync.RWMutex) (err error) {
 	return configGet(name,data,sync,mutex)
 }
@@ -30,7 +33,7 @@ func configGet(name string, data interface{}, sync bool, mutex *sync.RWMutex) (e
 
 	}
 	if err != nil {
-		if name != "resp" {
+		if !configOptional[name] {
 			panic(fmt.Sprintf("open %s config file failed:%s", name, err.Error()))
 		}
 
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const dumpRedacted = "******"
+
+var (
+	//dumpSecretKeys key包含这些时值被隐藏
+	dumpSecretKeys = []string{"password", "secret", "token"}
+
+	//dumpUserInfoRegexp 连接串中的user:password@
+	dumpUserInfoRegexp = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)?[^/@:\s,]+:[^/@\s,]+@`)
+)
+
+//Dump effective config with secrets redacted,key is config name,
+//通过*Get读取,热更新的code配置在对应的锁内转换
+func Dump() map[string]interface{} {
+	configs := map[string]interface{}{
+		"app":     appConfig,
+		"feature": FeatureGet(),
+		"log":     LogGet(),
+		"resp":    RespGet(),
+		"health":  HealthGet(),
+		"admin":   AdminGet(),
+	}
+	if FeatureZipkin() {
+		configs["zipkin"] = ZipkinGet()
+	}
+	if FeatureRedis() {
+		configs["redis"] = RedisGet()
+	}
+	if c := MysqlGetAll(); c != nil {
+		configs["mysql"] = c
+	}
+	if c := MongoGetAll(); c != nil {
+		configs["mongo"] = c
+	}
+	if c := GrpcGetAll(); c != nil {
+		configs["grpc"] = c
+	}
+	if c := EsGetAll(); c != nil {
+		configs["es"] = c
+	}
+	if c := HttpGetAll(); c != nil {
+		configs["http"] = c
+	}
+
+	dump := make(map[string]interface{})
+
+	for name, c := range configs {
+		if v, ok := dumpValue(c); ok {
+			dump[name] = v
+		}
+	}
+
+	mutexCodePri.RLock()
+	if v, ok := dumpValue(codeConfig.Private); ok {
+		dump["code_private"] = v
+	}
+	mutexCodePri.RUnlock()
+
+	mutexCodePub.RLock()
+	if v, ok := dumpValue(codeConfig.Public); ok {
+		dump["code_public"] = v
+	}
+	mutexCodePub.RUnlock()
+
+	return dump
+}
+
+//dumpValue 转为通用的map再隐藏
+func dumpValue(c interface{}) (interface{}, bool) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, false
+	}
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		return nil, false
+	}
+	return dumpRedact("", v), true
+}
+
+func dumpRedact(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = dumpRedact(k, item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = dumpRedact(key, item)
+		}
+		return value
+	case string:
+		lower := strings.ToLower(key)
+		for _, secret := range dumpSecretKeys {
+			if strings.Contains(lower, secret) && value != "" {
+				return dumpRedacted
+			}
+		}
+		return dumpUserInfoRegexp.ReplaceAllString(value, "${1}"+dumpRedacted+"@")
+	}
+	return v
+}
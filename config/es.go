@@ -47,3 +47,8 @@ func EsGet(service string) *EsConf {
 	}
 	return g
 }
+
+//EsGetAll get all config,key is service
+func EsGetAll() map[string]*EsConf {
+	return esConfig
+}
@@ -8,6 +8,7 @@ type GrpcConf struct {
 	Service  string
 	Insecure bool
 	Conn     []string
+	Internal bool //内部服务,调用时传递baggage(user id,tenant id等),外部服务不传
 }
 
 var (
@@ -48,3 +49,8 @@ func GrpcGet(service string) *GrpcConf {
 	}
 	return g
 }
+
+//GrpcGetAll get all config,key is service
+func GrpcGetAll() map[string]*GrpcConf {
+	return grpcConfig
+}
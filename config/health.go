@@ -0,0 +1,34 @@
+package config
+
+type Health struct {
+	Timeout  int //ms,每个checker的超时
+	CacheTtl int //ms,结果缓存时间,避免探针频繁访问后端
+}
+
+var (
+	healthConfig *Health
+)
+
+func init() {
+	healthConfig = &Health{}
+
+	err := configGet("health", healthConfig, false, nil)
+
+	if err != nil {
+		healthConfig = configHealthGetDefault()
+	}
+	if healthConfig.Timeout <= 0 {
+		healthConfig.Timeout = configHealthGetDefault().Timeout
+	}
+}
+
+func configHealthGetDefault() *Health {
+	return &Health{Timeout: 1000, CacheTtl: 2000}
+}
+
+func HealthGet() *Health {
+	if healthConfig == nil {
+		panic("health config is nil")
+	}
+	return healthConfig
+}
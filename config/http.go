@@ -15,8 +15,10 @@ type HttpConf struct {
 }
 
 type HttpConn struct {
-	Url     string
-	Timeout time.Duration
+	Url      string
+	Timeout  time.Duration
+	Health   string //健康检查的path,为空不检查
+	Internal bool   //内部服务,请求时传递baggage(user id,tenant id等),外部服务不传
 }
 
 type HttpPath struct {
@@ -76,3 +78,8 @@ func HttpGetPath(conf *HttpConf, key string) string {
 	}
 	return ""
 }
+
+//HttpGetAll get all config,key is service
+func HttpGetAll() map[string]*HttpConf {
+	return httpConfig
+}
@@ -1,11 +1,31 @@
 package config
 
+import "fmt"
+
+const (
+	MysqlShardHash  = "hash"
+	MysqlShardRange = "range"
+)
+
 type Mysql struct {
 	Mysql []MysqlConf
 }
 type MysqlConf struct {
-	Db   string
-	Conn MysqlConn
+	Db     string
+	Conn   MysqlConn
+	Shards []MysqlShard
+}
+
+//MysqlShard 分库分表规则,物理表为Table_00..Table_{Tables-1},按顺序均分到Dbs
+type MysqlShard struct {
+	Table  string
+	Key    string   //分片字段
+	Type   string   //hash,range
+	Tables int      //总表数
+	Dbs    []string //物理库,对应Mysql中的Db,为空不分库
+	Ranges []int64  //range时每张表的上界(不含),长度为Tables
+
+	Concurrency int //ShardEach同时执行的分片数,0时为Pool.Max
 }
 
 type MysqlConn struct {
@@ -13,12 +33,24 @@ type MysqlConn struct {
 	Write  MysqlBase
 	Reads  []MysqlBase
 	Pool   MysqlPool
+	Sticky int //ms,写后读主库的时间窗口,0时为默认的1000,<0时写后不读主库
+	Check  MysqlCheck
+	Slow   int  //ms,慢查询阈值,超过打日志,0不记录
+	Log    bool //打印所有sql,dev环境总是打印
 }
 type MysqlBase struct {
 	Address  string
 	Port     int
 	User     string
 	Password string
+	Weight   int //读库权重,<=0为1
+}
+
+//MysqlCheck 读库健康检查,失败的读库被摘除,恢复后重新加入
+type MysqlCheck struct {
+	Interval int //ms,0不检查
+	Timeout  int //ms
+	MaxLag   int //秒,主从延迟超过摘除,0不检查
 }
 
 type MysqlPool struct {
@@ -27,6 +59,9 @@ type MysqlPool struct {
 	LifeTimeSeconds int
 }
 
+//mysqlStickyDefault ms,一般的主从延迟在1秒内
+const mysqlStickyDefault = 1000
+
 var (
 	mysqlConfig map[string]MysqlConf
 )
@@ -44,17 +79,61 @@ func init() {
 		mysqlConfig = make(map[string]MysqlConf)
 
 		for i, c := range config.Mysql {
+			if c.Conn.Sticky == 0 {
+				config.Mysql[i].Conn.Sticky = mysqlStickyDefault
+			}
 			mysqlConfig[c.Db] = config.Mysql[i]
 		}
+
+		for _, c := range config.Mysql {
+			for _, shard := range c.Shards {
+				if err = configMysqlShardCheck(shard); err != nil {
+					panic(fmt.Sprintf("mysql %s shard %s config is invalid:%s", c.Db, shard.Table, err.Error()))
+				}
+			}
+		}
 	}
 }
 
 func configMysqlGetDefault() *Mysql {
 	return &Mysql{Mysql: []MysqlConf{MysqlConf{
 		Db: "tgo",
-		Conn: MysqlConn{Write: MysqlBase{"ip", 33062, "user", "password"},
-			Reads: []MysqlBase{MysqlBase{"ip", 3306, "user", "password"}},
-			Pool:  MysqlPool{Max: 16, IdleMax: 5, LifeTimeSeconds: 0}}}}}
+		Conn: MysqlConn{Write: MysqlBase{"ip", 33062, "user", "password", 0},
+			Reads: []MysqlBase{MysqlBase{"ip", 3306, "user", "password", 1}},
+			Pool:  MysqlPool{Max: 16, IdleMax: 5, LifeTimeSeconds: 0},
+			Check: MysqlCheck{Interval: 5000, Timeout: 1000, MaxLag: 0},
+			Sticky: mysqlStickyDefault,
+			Slow:   500}}}}
+}
+
+func configMysqlShardCheck(shard MysqlShard) error {
+	if shard.Table == "" || shard.Key == "" || shard.Tables <= 0 {
+		return fmt.Errorf("Table,Key and Tables are required")
+	}
+	if len(shard.Dbs) > shard.Tables {
+		return fmt.Errorf("Dbs is more than Tables")
+	}
+	for _, db := range shard.Dbs {
+		if _, ok := mysqlConfig[db]; !ok {
+			return fmt.Errorf("db %s not found", db)
+		}
+	}
+
+	switch shard.Type {
+	case MysqlShardHash:
+	case MysqlShardRange:
+		if len(shard.Ranges) != shard.Tables {
+			return fmt.Errorf("length of Ranges should be Tables")
+		}
+		for i := 1; i < len(shard.Ranges); i++ {
+			if shard.Ranges[i] <= shard.Ranges[i-1] {
+				return fmt.Errorf("Ranges should be ascending")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown type %s", shard.Type)
+	}
+	return nil
 }
 
 func MysqlGet(dbName string) MysqlConf {
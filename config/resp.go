@@ -1,9 +1,63 @@
 package config
 
+import (
+	"github.com/tonyjt/tgo_v2/pconst"
+)
+
 type Resp struct {
-	Code string
-	Msg  string
-	Data string
+	Code     string
+	Msg      string
+	Data     string
+	Details  string //TError.Details的key,为空则不输出
+	Status   RespStatus
+	Page     RespPage
+	Jsonp    RespJsonp
+	Hooks    RespHooks
+	Validate RespValidate
+}
+
+//RespValidate request validation config
+type RespValidate struct {
+	Code int            //校验失败的code
+	Tags map[string]int //校验tag:code,字段错误信息为CodeGetMsg(code),{field}和{param}会被替换
+}
+
+//RespHooks keys of values added to envelope,empty means not added,默认都为空,不改变已有的envelope
+type RespHooks struct {
+	TraceId   string
+	RequestId string
+	Time      string
+	Version   string //值为app配置的Version
+}
+
+//RespJsonp jsonp config
+type RespJsonp struct {
+	Disable bool     //关闭jsonp
+	Origins []string //允许jsonp的referer,如https://m.example.com,http://*.example.com,没有scheme只允许https,为空不限制
+}
+
+//RespPage keys of page envelope
+type RespPage struct {
+	List    string
+	Total   string
+	Page    string
+	Size    string
+	HasMore string
+	Cursor  string
+}
+
+//RespStatus http status mapping of codes
+type RespStatus struct {
+	Enable bool              //开启后ResponseJson按code返回http status
+	Codes  map[int]int       //code:status
+	Ranges []RespStatusRange //code范围:status,Codes优先
+}
+
+//RespStatusRange code range [Min,Max] to http status
+type RespStatusRange struct {
+	Min    int
+	Max    int
+	Status int
 }
 
 var (
@@ -21,13 +75,69 @@ func init() {
 		respConfig = defaultConfig
 	}
 
+	configRespPageFill(&respConfig.Page)
+
+	if respConfig.Validate.Code == 0 {
+		respConfig.Validate.Code = pconst.ERROR_REQUEST_VALIDATE
+	}
+
 	return
 }
 
 func configRespGetDefault() *Resp {
-	return &Resp{Code: "code", Msg: "msg", Data: "data"}
+	return &Resp{Code: "code", Msg: "msg", Data: "data", Page: configRespPageGetDefault()}
+}
+
+func configRespPageGetDefault() RespPage {
+	return RespPage{List: "list", Total: "total", Page: "page", Size: "size", HasMore: "has_more", Cursor: "next_cursor"}
+}
+
+//configRespPageFill 未配置的key使用默认值
+func configRespPageFill(page *RespPage) {
+	dft := configRespPageGetDefault()
+
+	if page.List == "" {
+		page.List = dft.List
+	}
+	if page.Total == "" {
+		page.Total = dft.Total
+	}
+	if page.Page == "" {
+		page.Page = dft.Page
+	}
+	if page.Size == "" {
+		page.Size = dft.Size
+	}
+	if page.HasMore == "" {
+		page.HasMore = dft.HasMore
+	}
+	if page.Cursor == "" {
+		page.Cursor = dft.Cursor
+	}
 }
 
 func RespGet() *Resp {
 	return respConfig
 }
+
+//RespGetStatus get http status of code, return 0 if not configured
+func RespGetStatus(code int) int {
+	conf := RespGet().Status
+
+	status, ok := conf.Codes[code]
+
+	if !ok {
+		for _, r := range conf.Ranges {
+			if code >= r.Min && code <= r.Max {
+				status = r.Status
+				ok = true
+				break
+			}
+		}
+	}
+
+	if !ok || status < 100 || status > 599 {
+		return 0
+	}
+	return status
+}
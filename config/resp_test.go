@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRespGetStatus(t *testing.T) {
+
+	if status := RespGetStatus(10302); status != 503 {
+		t.Errorf("code 10302 status:%d", status)
+	}
+
+	if status := RespGetStatus(10001); status != 500 {
+		t.Errorf("code 10001 status:%d", status)
+	}
+
+	if status := RespGetStatus(1001); status != 200 {
+		t.Errorf("code 1001 status:%d", status)
+	}
+}
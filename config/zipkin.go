@@ -2,10 +2,29 @@ package config
 
 type ConfigZipkin struct {
 	ServiceName       string
+	Backend           string //zipkin(默认,v1),zipkin-v2,jaeger,otlp,stdout,file,noop
 	CollectorEndpoint string
+	File              string //file backend的文件
 	Debug             bool
 	SameSpan          bool
 	TraceID128Bit     bool
+	Sampler           ZipkinSampler
+	ExcludePaths      []string //不trace的http路径,*结尾为前缀匹配,如健康检查
+	Baggage           ZipkinBaggage
+}
+
+//ZipkinBaggage trust of incoming baggage(http header,grpc metadata) and X-Request-Id header,默认不信任
+type ZipkinBaggage struct {
+	Trusted bool     //上游都是内部服务时开启,edge服务保持false,user id等只能在鉴权后用BaggageSet设置
+	Proxies []string //可信的上游ip或cidr,按连接的地址判断,不使用X-Forwarded-For
+}
+
+type ZipkinSampler struct {
+	Type        string   //const(默认),probabilistic,ratelimiting
+	Param       float64  //const:0或1,probabilistic:采样率0-1,ratelimiting:每秒采样数
+	Routes      []string //总是采样的路由
+	DebugHeader string   //请求的此header等于DebugSecret时总是采样
+	DebugSecret string   //为空时不接受DebugHeader,防止外部请求绕过采样
 }
 
 var (
@@ -22,6 +41,10 @@ func init() {
 			defaultZipkinConfig := configZipkinGetDefault()
 
 			zipkinConfig = defaultZipkinConfig
+		} else if zipkinConfig.Sampler.Type == "" {
+			//未配置采样时全部采样
+			zipkinConfig.Sampler.Type = "const"
+			zipkinConfig.Sampler.Param = 1
 		}
 	}
 }
@@ -29,10 +52,19 @@ func init() {
 func configZipkinGetDefault() *ConfigZipkin {
 	return &ConfigZipkin{
 		ServiceName:       "tgov2",
+		Backend:           "zipkin",
 		CollectorEndpoint: "172.172.177.19:9411/api/v1/spans",
 		Debug:             false,
 		SameSpan:          true,
-		TraceID128Bit:     true}
+		TraceID128Bit:     true,
+		Sampler:           configZipkinSamplerGetDefault()}
+}
+
+func configZipkinSamplerGetDefault() ZipkinSampler {
+	return ZipkinSampler{
+		Type:        "const",
+		Param:       1,
+		DebugHeader: "X-Trace-Debug"}
 }
 
 func ZipkinGet() *ConfigZipkin {
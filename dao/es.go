@@ -22,11 +22,7 @@ type Es struct {
 
 //ZipkinNewSpan new zipkin span for es
 func (p *Es) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("es:%s:%s", p.Service, name))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("es:%s:%s", p.Service, name), "es", p.Service, name)
 }
 func (p *Es) proccessError(span opentracing.Span, err error, msg string) error {
 	log.Error(msg)
@@ -146,7 +142,7 @@ func (p *Es) Delete(ctx context.Context, id string) (err error) {
 func (p *Es) Search(ctx context.Context, query elastic.Query, typ interface{}, from int, size int,
 	sorters ...elastic.Sorter) (data []interface{}, err error) {
 
-	span, ctx := p.ZipkinNewSpan(ctx, "delete")
+	span, ctx := p.ZipkinNewSpan(ctx, "search")
 	if span != nil {
 		defer span.Finish()
 	}
@@ -160,7 +156,8 @@ func (p *Es) Search(ctx context.Context, query elastic.Query, typ interface{}, f
 		From(from).Size(size).SortBy(sorters...).Do(ctx)
 	if err != nil {
 		msg := fmt.Sprintf("es search error :%s", err.Error())
-		p.proccessError(span, err, msg)
+		err = p.proccessError(span, err, msg)
+		return
 	}
 
 	data = res.Each(reflect.TypeOf(typ))
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/olivere/elastic"
+	"github.com/tonyjt/tgo_v2/tracing/tracetest"
 	"testing"
 )
 
@@ -48,3 +49,18 @@ func TestEs_Invoke(t *testing.T) {
 	}
 
 }
+
+func TestEs_Search(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
+	es := &Es{Service: "tgo", Index: "xingxiangrong_loan", Type: "1"}
+
+	_, err := es.Search(context.Background(), elastic.NewMatchAllQuery(), loan{}, 0, 10)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	r.AssertSpan(t, "es:tgo:search", nil)
+}
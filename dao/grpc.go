@@ -9,8 +9,11 @@ import (
 	"github.com/tonyjt/tgo_v2/log"
 	"github.com/tonyjt/tgo_v2/pconst"
 	"github.com/tonyjt/tgo_v2/terror"
+	"github.com/tonyjt/tgo_v2/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	//"google.golang.org/grpc/resolver"
 	"github.com/opentracing/opentracing-go/ext"
 	"google.golang.org/grpc/connectivity"
@@ -36,11 +39,7 @@ type Grpc struct {
 }
 
 func (p *Grpc) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("grpc:%s:%s", p.Service, name))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("grpc:%s:%s", p.Service, name), "grpc", p.Service, name)
 }
 func (p *Grpc) proccessError(span opentracing.Span, err error, msg string) error {
 	log.Error(msg)
@@ -90,10 +89,14 @@ func (p *Grpc) GetConn(ctx context.Context) (conn *grpc.ClientConn, err error) {
 			if conf.Insecure {
 				dialOptions = append(dialOptions, grpc.WithInsecure())
 			}
+			//stream的日志和错误转换在trace外层,trace记录原始的grpc错误
+			streamInterceptors := []grpc.StreamClientInterceptor{p.streamClientInterceptor()}
 			if config.FeatureZipkin() {
 				tracer := opentracing.GlobalTracer()
 				dialOptions = append(dialOptions, grpc.WithUnaryInterceptor(otgrpc.OpenTracingClientInterceptor(tracer)))
+				streamInterceptors = append(streamInterceptors, otgrpc.OpenTracingStreamClientInterceptor(tracer))
 			}
+			dialOptions = append(dialOptions, grpc.WithStreamInterceptor(grpcStreamClientChain(streamInterceptors...)))
 			r, cleanup := manual.GenerateAndRegisterManualResolver()
 			defer cleanup()
 
@@ -101,7 +104,7 @@ func (p *Grpc) GetConn(ctx context.Context) (conn *grpc.ClientConn, err error) {
 
 			if err != nil {
 				msg := fmt.Sprintf("dail failed,service:%s,error:%s", p.Service, err.Error())
-				err = terror.New(pconst.ERROR_GRPC_DAIL)
+				err = terror.New(pconst.ERROR_GRPC_DAIL).SetRetryable(true)
 				p.proccessError(span, err, msg)
 				return
 			}
@@ -148,14 +151,31 @@ func (p *Grpc) Invoke(ctx context.Context, conn *grpc.ClientConn, funcName strin
 		defer p.CloseConn(ctx, conn)
 	}
 
-	err = funcInvoke(ctx)
+	err = funcInvoke(p.injectBaggage(ctx))
 
 	if err != nil {
 		msg := fmt.Sprintf("grpc error,conn:%s,funcName:%s,error :%s", p.Service, funcName, err.Error())
-		err = terror.New(pconst.ERROR_GRPC_INVOKE)
+		err = terror.New(pconst.ERROR_GRPC_INVOKE).SetRetryable(grpcRetryable(err))
 		p.proccessError(span, err, msg)
 	}
 
 	return
 
 }
+
+//injectBaggage 只向内部服务(config.GrpcConf.Internal)传递baggage,避免user id等泄露给外部服务
+func (p *Grpc) injectBaggage(ctx context.Context) context.Context {
+	if conf, ok := config.GrpcGetAll()[p.Service]; ok && conf.Internal {
+		return tracing.InjectBaggageGrpc(ctx)
+	}
+	return ctx
+}
+
+//grpcRetryable whether grpc error can be retried
+func grpcRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"context"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"google.golang.org/grpc"
+	"io"
+)
+
+//grpcClientStream ClientStream,和unary的Invoke一样记录日志并转换错误
+type grpcClientStream struct {
+	grpc.ClientStream
+	service string
+	method  string
+}
+
+func (s *grpcClientStream) SendMsg(m interface{}) error {
+	return s.processError(s.ClientStream.SendMsg(m), "send")
+}
+
+func (s *grpcClientStream) RecvMsg(m interface{}) error {
+	return s.processError(s.ClientStream.RecvMsg(m), "recv")
+}
+
+func (s *grpcClientStream) CloseSend() error {
+	return s.processError(s.ClientStream.CloseSend(), "closesend")
+}
+
+func (s *grpcClientStream) processError(err error, op string) error {
+	//io.EOF表示stream正常结束,不转换
+	if err == nil || err == io.EOF {
+		return err
+	}
+	log.Errorf("grpc stream %s error,conn:%s,method:%s,error :%s", op, s.service, s.method, err.Error())
+
+	return terror.New(pconst.ERROR_GRPC_STREAM).SetRetryable(grpcRetryable(err))
+}
+
+//streamClientInterceptor log and convert error of client stream
+func (p *Grpc) streamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		cs, err := streamer(p.injectBaggage(ctx), desc, cc, method, opts...)
+
+		if err != nil {
+			log.Errorf("grpc stream error,conn:%s,method:%s,error :%s", p.Service, method, err.Error())
+			return nil, terror.New(pconst.ERROR_GRPC_STREAM).SetRetryable(grpcRetryable(err))
+		}
+
+		return &grpcClientStream{ClientStream: cs, service: p.Service, method: method}, nil
+	}
+}
+
+//grpcStreamClientChain chain stream client interceptors,第一个在最外层
+func grpcStreamClientChain(interceptors ...grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		chained := streamer
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+				opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return interceptor(ctx, desc, cc, method, next, opts...)
+			}
+		}
+		return chained(ctx, desc, cc, method, opts...)
+	}
+}
@@ -3,7 +3,10 @@ package dao
 import (
 	"context"
 	"fmt"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"testing"
 )
 
@@ -57,3 +60,60 @@ func BenchmarkDaoGRPC_GetConn(b *testing.B) {
 	}
 
 }
+
+func TestGrpcStreamClientChain(t *testing.T) {
+	var order []int
+
+	interceptor := func(i int) grpc.StreamClientInterceptor {
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+			streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			order = append(order, i)
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+	}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		order = append(order, 0)
+		return nil, nil
+	}
+
+	chain := grpcStreamClientChain(interceptor(1), interceptor(2))
+	chain(context.Background(), &grpc.StreamDesc{}, nil, "/test", streamer)
+
+	if fmt.Sprint(order) != "[1 2 0]" {
+		t.Errorf("chain order:%v", order)
+	}
+}
+
+func TestDaoGRPC_InvokeBaggage(t *testing.T) {
+	conf := config.GrpcGet("tgo")
+	internal := conf.Internal
+	defer func() {
+		conf.Internal = internal
+	}()
+
+	ctx := tracing.SetUserId(context.Background(), "10001")
+	daoGrpc := &Grpc{Service: "tgo"}
+
+	var md metadata.MD
+	invoke := func(ctx context.Context) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	conf.Internal = true
+	if err := daoGrpc.Invoke(ctx, nil, "test", invoke); err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Get(tracing.HeaderBaggage)) == 0 {
+		t.Error("baggage should be injected to internal service")
+	}
+
+	conf.Internal = false
+	if err := daoGrpc.Invoke(ctx, nil, "test", invoke); err != nil {
+		t.Fatal(err)
+	}
+	if values := md.Get(tracing.HeaderBaggage); len(values) > 0 {
+		t.Errorf("baggage should not be injected to external service:%v", values)
+	}
+}
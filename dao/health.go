@@ -0,0 +1,205 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"github.com/olivere/elastic"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/health"
+	"github.com/youtube/vitess/go/pools"
+	"google.golang.org/grpc/connectivity"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//init mysql writer,mongo,redis为critical,mysql reader和grpc,es,http服务down时不影响readiness
+func init() {
+	if config.FeatureMysql() {
+		for db, conf := range config.MysqlGetAll() {
+			health.Register(fmt.Sprintf("mysql:%s:write", db), healthMysql(db, -1), true)
+
+			for i := range conf.Conn.Reads {
+				health.Register(fmt.Sprintf("mysql:%s:read:%d", db, i), healthMysql(db, i), false)
+			}
+		}
+	}
+	if config.FeatureMongo() {
+		for db := range config.MongoGetAll() {
+			health.Register(fmt.Sprintf("mongo:%s", db), healthMongo(db), true)
+		}
+	}
+	if config.FeatureRedis() {
+		health.Register("redis:persist", healthRedis(true), true)
+		health.Register("redis:unpersist", healthRedis(false), true)
+	}
+	if config.FeatureGrpc() {
+		for service := range config.GrpcGetAll() {
+			health.Register(fmt.Sprintf("grpc:%s", service), healthGrpc(service), false)
+		}
+	}
+	if config.FeatureEs() {
+		for service := range config.EsGetAll() {
+			health.Register(fmt.Sprintf("es:%s", service), healthEs(service), false)
+		}
+	}
+	if config.FeatureHTTP() {
+		for service, conf := range config.HttpGetAll() {
+			if conf.Conn.Health != "" {
+				health.Register(fmt.Sprintf("http:%s", service), healthHttp(conf), false)
+			}
+		}
+	}
+}
+
+//healthMysql ping writer,index>=0时ping对应的reader
+func healthMysql(db string, index int) health.Checker {
+	return func(ctx context.Context) error {
+		if index < 0 {
+			d, ok := dbMysqlWrite[db]
+			if !ok || d == nil {
+				return fmt.Errorf("mysql %s writer not connected", db)
+			}
+			return d.DB().PingContext(ctx)
+		}
+
+		reads := dbMysqlReads[db]
+		if index >= len(reads) {
+			return fmt.Errorf("mysql %s reader %d not connected", db, index)
+		}
+		d, up := reads[index].get()
+		if d == nil {
+			return fmt.Errorf("mysql %s reader %d not connected", db, index)
+		}
+		if !up {
+			return fmt.Errorf("mysql %s reader %d is ejected", db, index)
+		}
+		return d.DB().PingContext(ctx)
+	}
+}
+
+//healthMongo mgo不支持ctx,按ctx的deadline设置session超时
+func healthMongo(db string) health.Checker {
+	return func(ctx context.Context) error {
+		if sessionMongo == nil {
+			return fmt.Errorf("mongo session is nil")
+		}
+		session := sessionMongo.Copy()
+		defer session.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline)
+			if timeout <= 0 {
+				return context.DeadlineExceeded
+			}
+			session.SetSyncTimeout(timeout)
+			session.SetSocketTimeout(timeout)
+		}
+
+		return session.DB(db).Run("ping", nil)
+	}
+}
+
+func healthRedis(isPersist bool) health.Checker {
+	return func(ctx context.Context) error {
+		var pool *pools.ResourcePool
+
+		if isPersist {
+			pool = persist
+		} else {
+			pool = unpersist
+		}
+		if pool == nil {
+			return fmt.Errorf("redis pool is nil")
+		}
+
+		r, err := pool.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return fmt.Errorf("redis pool resource is nil")
+		}
+
+		if _, err = r.(ResourceConn).Do("PING"); err != nil {
+			//连接可能已断开,关闭后放回nil,pool下次Get时重新创建
+			r.Close()
+			pool.Put(nil)
+			return err
+		}
+
+		pool.Put(r)
+		return nil
+	}
+}
+
+//healthGrpc 连接是懒加载的,没有连接时认为正常
+func healthGrpc(service string) health.Checker {
+	return func(ctx context.Context) error {
+		grpcConnMux.RLock()
+		conn := grpcConnMap[service]
+		grpcConnMux.RUnlock()
+
+		if conn == nil {
+			return nil
+		}
+		switch state := conn.GetState(); state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("grpc %s state %s", service, state.String())
+		}
+		return nil
+	}
+}
+
+//healthEs 每个service复用一个client,elastic.NewClient会启动sniff和healthcheck的goroutine
+func healthEs(service string) health.Checker {
+	var (
+		client *elastic.Client
+		mux    sync.Mutex
+	)
+
+	return func(ctx context.Context) error {
+		mux.Lock()
+		if client == nil {
+			c, err := (&Es{Service: service}).GetConn(ctx)
+			if err != nil {
+				mux.Unlock()
+				return err
+			}
+			client = c
+		}
+		c := client
+		mux.Unlock()
+
+		res, err := c.ClusterHealth().Do(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Status == "red" {
+			return fmt.Errorf("es %s cluster status red", service)
+		}
+		return nil
+	}
+}
+
+func healthHttp(conf *config.HttpConf) health.Checker {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodGet, conf.Conn.Url+conf.Conn.Health, nil)
+		if err != nil {
+			return err
+		}
+
+		client := http.Client{Timeout: conf.Conn.Timeout}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("http %s health status %d", conf.Service, resp.StatusCode)
+		}
+		return nil
+	}
+}
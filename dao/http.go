@@ -10,9 +10,12 @@ import (
 	"github.com/tonyjt/tgo_v2/log"
 	"github.com/tonyjt/tgo_v2/pconst"
 	"github.com/tonyjt/tgo_v2/terror"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type Http struct {
@@ -20,11 +23,7 @@ type Http struct {
 }
 
 func (p *Http) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("http:%s/%s", p.Service, name))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("http:%s/%s", p.Service, name), "http", p.Service, name)
 }
 func (p *Http) proccessError(span opentracing.Span, err error, msg string) error {
 	log.Error(msg)
@@ -53,13 +52,22 @@ func (p *Http) PostForm(ctx context.Context, pathKey string, data url.Values) (r
 		return
 	}
 
+	req, err := p.newRequest(ctx, span, http.MethodPost, u, strings.NewReader(data.Encode()))
+
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
 	client := http.Client{Timeout: conf.Conn.Timeout}
 
-	response, err = client.PostForm(u, data)
+	response, err = client.Do(req)
 
 	if err != nil {
 		msg := fmt.Sprintf("post form url:%s,err:%s", u, err.Error())
-		err = terror.New(pconst.ERROR_HTTP_POSTFORM)
+		//post不幂等,只有超时等临时错误可以重试
+		err = terror.New(pconst.ERROR_HTTP_POSTFORM).SetRetryable(terror.IsRetryable(err))
 		p.proccessError(span, err, msg)
 	} else if response == nil {
 		msg := fmt.Sprintf("post form url:%s,response is nil", u)
@@ -106,16 +114,23 @@ func (p *Http) Get(ctx context.Context, pathKey string, queryString string) (res
 		return
 	}
 
+	req, err := p.newRequest(ctx, span, http.MethodGet, fmt.Sprintf("%s?%s", u, queryString), nil)
+
+	if err != nil {
+		return
+	}
+
 	client := http.Client{Timeout: conf.Conn.Timeout}
 
-	response, err = client.Get(fmt.Sprintf("%s?%s", u, queryString))
+	response, err = client.Do(req)
 
 	if err != nil {
 		msg := fmt.Sprintf("get url:%s,err:%s", u, err.Error())
-		err = terror.New(pconst.ERROR_HTTP_POSTFORM)
+		//配置,tls等错误重试也不会成功
+		err = terror.New(pconst.ERROR_HTTP_POSTFORM).SetRetryable(terror.IsRetryable(err))
 		p.proccessError(span, err, msg)
 	} else if response == nil {
-		msg := fmt.Sprintf("post form url:%s,response is nil", u)
+		msg := fmt.Sprintf("get url:%s,response is nil", u)
 		err = terror.New(pconst.ERROR_HTTP_POSTFORM_RESPONSE)
 		p.proccessError(span, err, msg)
 	}
@@ -153,6 +168,35 @@ func (p *Http) UnmarshalBody(ctx context.Context, body []byte, data interface{})
 	return
 }
 
+//newRequest 创建请求并注入trace header,内部服务注入baggage
+func (p *Http) newRequest(ctx context.Context, span opentracing.Span, method string, u string, body io.Reader) (req *http.Request, err error) {
+	req, err = http.NewRequest(method, u, body)
+
+	if err != nil {
+		msg := fmt.Sprintf("new request url:%s,err:%s", u, err.Error())
+		err = terror.New(pconst.ERROR_HTTP_CONFIG)
+		p.proccessError(span, err, msg)
+		return
+	}
+
+	req = req.WithContext(ctx)
+
+	if conf := config.HttpGet(p.Service); conf != nil && conf.Conn.Internal {
+		tracing.InjectBaggage(ctx, req.Header)
+	}
+
+	if span != nil {
+		ext.SpanKindRPCClient.Set(span)
+		ext.HTTPMethod.Set(span, method)
+		ext.HTTPUrl.Set(span, u)
+
+		if errInject := tracing.Inject(span, req.Header); errInject != nil {
+			log.Errorf("inject trace header failed,url:%s,err:%s", u, errInject.Error())
+		}
+	}
+	return
+}
+
 func (p *Http) url(ctx context.Context, span opentracing.Span, conf *config.HttpConf, pathKey string) (url string, err error) {
 
 	if conf == nil {
@@ -0,0 +1,109 @@
+package dao
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"github.com/tonyjt/tgo_v2/tracing/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func testHttpServer(handler http.HandlerFunc) func() {
+	server := httptest.NewServer(handler)
+
+	conf := config.HttpGet("tgo")
+	url, path := conf.Conn.Url, conf.Paths[0].Path
+
+	conf.Conn.Url = server.URL
+	conf.Paths[0].Path = "/test1"
+
+	return func() {
+		conf.Conn.Url, conf.Paths[0].Path = url, path
+		server.Close()
+	}
+}
+
+func TestHttp_Get(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
+	var header http.Header
+	closer := testHttpServer(func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header
+		w.Write([]byte("ok"))
+	})
+	defer closer()
+
+	parent, ctx := opentracing.StartSpanFromContext(context.Background(), "parent")
+
+	h := &Http{Service: "tgo"}
+	body, err := h.GetAndReadAll(ctx, "test1", "a=1")
+	parent.Finish()
+
+	if err != nil {
+		t.Fatal(err)
+	} else if string(body) != "ok" {
+		t.Errorf("body:%s", string(body))
+	}
+
+	span := r.AssertSpan(t, "http:tgo/test1", map[string]interface{}{"http.method": "GET", "span.kind": "client"})
+	r.AssertError(t, "http:tgo/test1", false)
+	tracetest.AssertChild(t, r.AssertSpan(t, "parent", nil), span)
+
+	if header.Get(tracing.HeaderTraceparent) == "" && header.Get("Mockpfx-Ids-Traceid") == "" {
+		t.Errorf("trace header not injected:%v", header)
+	}
+}
+
+func TestHttp_PostFormError(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
+	closer := testHttpServer(func(w http.ResponseWriter, req *http.Request) {})
+	closer()
+
+	h := &Http{Service: "tgo"}
+	_, err := h.PostForm(context.Background(), "test1", url.Values{"a": []string{"1"}})
+
+	if err == nil {
+		t.Fatal("post to closed server should fail")
+	}
+	r.AssertError(t, "http:tgo/test1", true)
+}
+
+func TestHttp_Baggage(t *testing.T) {
+	var header http.Header
+	closer := testHttpServer(func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header
+	})
+	defer closer()
+
+	conf := config.HttpGet("tgo")
+	internal := conf.Conn.Internal
+	defer func() {
+		conf.Conn.Internal = internal
+	}()
+
+	ctx := tracing.SetUserId(context.Background(), "10001")
+	h := &Http{Service: "tgo"}
+
+	conf.Conn.Internal = true
+	if _, err := h.GetAndReadAll(ctx, "test1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get(tracing.HeaderBaggage) == "" {
+		t.Error("baggage should be injected to internal service")
+	}
+
+	conf.Conn.Internal = false
+	if _, err := h.GetAndReadAll(ctx, "test1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if value := header.Get(tracing.HeaderBaggage); value != "" {
+		t.Errorf("baggage should not be injected to external service:%s", value)
+	}
+}
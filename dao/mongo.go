@@ -83,6 +83,8 @@ var (
 
 func init() {
 	if config.FeatureMongo() {
+		//mgo的连接统计,StatsGet使用
+		mgo.SetStats(config.FeatureMetrics())
 
 		for _, c := range config.MongoGetAll() {
 			configMongo := c.Conn
@@ -232,11 +234,7 @@ func (p *Mongo) GetNextSequence(ctx context.Context) (seq int64, err error) {
 }
 
 func (p *Mongo) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("mongo:%s:%s", name, p.CollectionName))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("mongo:%s:%s", name, p.CollectionName), "mongo", p.CollectionName, name)
 }
 
 // Find
@@ -283,6 +281,99 @@ func (p *Mongo) Find(ctx context.Context, condition interface{}, limit int, skip
 	return errSelect
 }
 
+// FindPage find by page,page starts from 1,return total count
+func (p *Mongo) FindPage(ctx context.Context, condition interface{}, page int, size int, data interface{}, sortFields ...string) (total int, err error) {
+
+	total, err = p.Count(ctx, condition)
+
+	if err != nil || total == 0 {
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	skip := (page - 1) * size
+
+	if size > 0 && skip >= total {
+		return
+	}
+
+	err = p.Find(ctx, condition, size, skip, data, sortFields...)
+
+	return
+}
+
+// FindCursor find by cursor field(eg. _id),cursor is nil for first page,desc means field < cursor,
+// return next cursor(value of field of last item) and whether has more
+func (p *Mongo) FindCursor(ctx context.Context, condition interface{}, data interface{}, field string, cursor interface{}, size int, desc bool) (next interface{}, hasMore bool, err error) {
+
+	op, sortField := "$gt", field
+	if desc {
+		op, sortField = "$lt", "-"+field
+	}
+
+	if cursor != nil {
+		cursorCondition := bson.M{field: bson.M{op: cursor}}
+
+		if condition == nil {
+			condition = cursorCondition
+		} else {
+			condition = bson.M{"$and": []interface{}{condition, cursorCondition}}
+		}
+	}
+
+	limit := size
+	if size > 0 {
+		limit = size + 1
+	}
+
+	err = p.Find(ctx, condition, limit, 0, data, sortField)
+
+	if err != nil {
+		return
+	}
+
+	last, hasMore := pageTrim(data, size)
+
+	if last != nil {
+		next, err = mongoFieldValue(last, field)
+
+		if err != nil {
+			err = p.processError(nil, err, pconst.ERROR_MONGO_ALL, "mongo %s find cursor failed:%s", p.CollectionName, err.Error())
+		}
+	}
+	return
+}
+
+//mongoFieldValue 按bson的字段名取值,field可以是a.b的形式
+func mongoFieldValue(item interface{}, field string) (interface{}, error) {
+	raw, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+
+	if err = bson.Unmarshal(raw, doc); err != nil {
+		return nil, err
+	}
+
+	var value interface{} = doc
+
+	for _, key := range strings.Split(field, ".") {
+		m, ok := value.(bson.M)
+		if !ok {
+			return nil, fmt.Errorf("cursor field %s not found", field)
+		}
+		if value, ok = m[key]; !ok {
+			return nil, fmt.Errorf("cursor field %s not found", field)
+		}
+	}
+	return value, nil
+}
+
 // FindById
 func (p *Mongo) FindById(ctx context.Context, id int64, data interface{}) error {
 	span, ctx := p.ZipkinNewSpan(ctx, "findbyid")
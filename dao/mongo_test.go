@@ -1 +1,29 @@
 package dao
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
+
+func TestMongoFieldValue(t *testing.T) {
+	type item struct {
+		ModelMongo `bson:",inline"`
+		Sn         string
+		Extra      bson.M
+	}
+
+	data := &item{ModelMongo: ModelMongo{Id: 9}, Sn: "a1", Extra: bson.M{"score": 3.5}}
+
+	if v, err := mongoFieldValue(data, "_id"); err != nil || v != int64(9) {
+		t.Errorf("_id should be 9:%v,%v", v, err)
+	}
+	if v, err := mongoFieldValue(data, "sn"); err != nil || v != "a1" {
+		t.Errorf("sn should be a1:%v,%v", v, err)
+	}
+	if v, err := mongoFieldValue(data, "extra.score"); err != nil || v != 3.5 {
+		t.Errorf("extra.score should be 3.5:%v,%v", v, err)
+	}
+	if _, err := mongoFieldValue(data, "sn.x"); err == nil {
+		t.Error("missing field should fail")
+	}
+}
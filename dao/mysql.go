@@ -11,13 +11,17 @@ import (
 	"github.com/tonyjt/tgo_v2/log"
 	"github.com/tonyjt/tgo_v2/pconst"
 	"github.com/tonyjt/tgo_v2/terror"
-	"math/rand"
+	"regexp"
+	"strings"
 	"time"
 )
 
 var (
 	dbMysqlWrite map[string]*gorm.DB
-	dbMysqlReads map[string][]*gorm.DB
+	dbMysqlReads map[string][]*mysqlReplica
+
+	//mysqlColumnRegexp 拼接到sql中的列名,可以带表名
+	mysqlColumnRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
 )
 
 type IModelMysql interface {
@@ -51,13 +55,13 @@ func init() {
 	if config.FeatureMysql() {
 
 		dbMysqlWrite = make(map[string]*gorm.DB)
-		dbMysqlReads = make(map[string][]*gorm.DB)
+		dbMysqlReads = make(map[string][]*mysqlReplica)
 
 		for _, conf := range config.MysqlGetAll() {
 
 			var err error
 			var dbWrite *gorm.DB
-			dbWrite, err = initDb(conf.Conn.DbName, conf.Conn.Write, conf.Conn.Pool)
+			dbWrite, err = initDb(conf, conf.Conn.Write)
 
 			if err != nil {
 				panic("connect to mysql write server failed" + err.Error())
@@ -65,26 +69,21 @@ func init() {
 
 			dbMysqlWrite[conf.Db] = dbWrite
 
+			//连不上的读库保留,由健康检查重连
 			for _, c := range conf.Conn.Reads {
-				d, err := initDb(conf.Conn.DbName, c, conf.Conn.Pool)
-
-				if err == nil {
-					dbMysqlReads[conf.Db] = append(dbMysqlReads[conf.Db], d)
-				} else {
-					log.Errorf("mysql read init failed:%+v", err)
-				}
+				dbMysqlReads[conf.Db] = append(dbMysqlReads[conf.Db], newMysqlReplica(conf, c))
 			}
 
-			if len(dbMysqlReads) == 0 {
-				dbMysqlReads[conf.Db] = append(dbMysqlReads[conf.Db], dbWrite)
-			}
+			mysqlReplicaCheckStart(conf, dbMysqlReads[conf.Db])
 		}
 	}
 }
 
-func initDb(dbName string, configMysql config.MysqlBase, configPool config.MysqlPool) (*gorm.DB, error) {
+func initDb(conf config.MysqlConf, configMysql config.MysqlBase) (*gorm.DB, error) {
+	configPool := conf.Conn.Pool
+
 	addr := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4,utf8&parseTime=True&loc=Local", configMysql.User,
-		configMysql.Password, configMysql.Address, configMysql.Port, dbName)
+		configMysql.Password, configMysql.Address, configMysql.Port, conf.Conn.DbName)
 
 	resultDb, err := gorm.Open("mysql", addr)
 
@@ -98,11 +97,13 @@ func initDb(dbName string, configMysql config.MysqlBase, configPool config.Mysql
 
 	resultDb.DB().Ping()
 
-	if config.AppEnvIsDev() {
+	if config.AppEnvIsDev() || conf.Conn.Log {
 		resultDb.LogMode(true)
 	}
 
-	return resultDb, nil
+	mysqlCallbackRegister(resultDb)
+
+	return resultDb.Set(mysqlDbKey, conf.Db), nil
 }
 
 type Mysql struct {
@@ -128,11 +129,15 @@ func (p *Mysql) getDbName() string {
 	return ""
 }
 
-// GetWriteOrm
+// GetWriteOrm ctx中有事务(TransactionContext)时返回事务
 func (p *Mysql) GetWriteOrm(ctx context.Context) (*gorm.DB, error) {
 
 	dbName := p.getDbName()
 
+	if tx := mysqlTxGet(ctx, dbName); tx != nil {
+		return tx, nil
+	}
+
 	span, ctx := p.ZipkinNewSpan(ctx, dbName+":getWriteOrm")
 
 	if span != nil {
@@ -147,17 +152,26 @@ func (p *Mysql) GetWriteOrm(ctx context.Context) (*gorm.DB, error) {
 	return dbMysqlWrite[dbName], nil
 }
 
-// GetReadOrm
+// GetReadOrm 默认读从库,WithPrimary或开启了WithSticky且ctx内写过该db时读主库,
+// 没有WithSticky的ctx写后立即读可能读到从库的旧数据,ctx中有事务(TransactionContext)时使用事务
 func (p *Mysql) GetReadOrm(ctx context.Context) (*gorm.DB, error) {
+	if tx := mysqlTxGet(ctx, p.getDbName()); tx != nil {
+		return tx, nil
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "getReadOrm")
 
 	if span != nil {
 		defer span.Finish()
 	}
-	dbName := p.getDbName()
-	conf := dbMysqlReads[dbName]
-	if len(conf) == 0 {
+	if p.readPrimary(ctx) {
+		if span != nil {
+			span.SetTag("primary", true)
+		}
+		return p.GetWriteOrm(ctx)
+	}
+
+	if dbMysqlReads == nil {
 		err := terror.New(pconst.ERROR_MYSQL_READ_EMPTY)
 		ext.Error.Set(span, true)
 		span.SetTag("err:getorm", err)
@@ -165,35 +179,35 @@ func (p *Mysql) GetReadOrm(ctx context.Context) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	var index int
-	if len(conf) > 1 {
-		rand.Seed(time.Now().UnixNano())
-
-		index = rand.Intn(len(conf) - 1)
-
-	} else {
-		index = 0
+	if db := mysqlReplicaPick(dbMysqlReads[p.getDbName()]); db != nil {
+		return db, nil
 	}
 
-	return conf[index], nil
+	//没有可用的读库时读主库
+	if span != nil {
+		span.SetTag("fallback", true)
+	}
+	return p.GetWriteOrm(ctx)
 }
 
 func (p *Mysql) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("mysql:%s:%s", name, p.TableName))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("mysql:%s:%s", name, p.TableName), "mysql", p.getDbName(), name)
 }
 
 // Insert
 func (p *Mysql) Insert(ctx context.Context, db *gorm.DB, model IModelMysql) (err error) {
+	p, err = p.shardRoute(ctx, model)
+	if err != nil {
+		return err
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "insert")
 	if span != nil {
 		defer span.Finish()
 	}
 
+	p.markWrite(ctx)
+
 	if db == nil {
 		db, err = p.GetWriteOrm(ctx)
 
@@ -204,7 +218,7 @@ func (p *Mysql) Insert(ctx context.Context, db *gorm.DB, model IModelMysql) (err
 	}
 	model.InitTime(time.Now())
 
-	errInsert := db.Table(p.TableName).Create(model).Error
+	errInsert := mysqlSpanSet(db.Table(p.TableName), span).Create(model).Error
 
 	if errInsert != nil {
 		err = p.processError(span, errInsert, pconst.ERROR_MYSQL_INSERT, "insert data error")
@@ -220,6 +234,10 @@ func (p *Mysql) Select(ctx context.Context, db *gorm.DB, query interface{}, quer
 
 // Select
 func (p *Mysql) SelectPlus(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}, data interface{}, skip int, limit int, fields []string, sort string) (err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return err
+	}
 	span, ctx := p.ZipkinNewSpan(ctx, "select")
 	if span != nil {
 		defer span.Finish()
@@ -235,7 +253,14 @@ func (p *Mysql) SelectPlus(ctx context.Context, db *gorm.DB, query interface{},
 		//defer db.Close()
 	}
 
-	db = db.Table(p.TableName).Where(query, queryArgs...)
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, data)
+	if err != nil {
+		return p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "select query error")
+	}
+
+	if q, ok := query.(*MysqlQuery); ok {
+		fields, skip, limit, sort = mysqlQueryDefaults(q, fields, skip, limit, sort)
+	}
 
 	var errFind error
 	if len(fields) > 0 {
@@ -264,14 +289,141 @@ func (p *Mysql) SelectPlus(ctx context.Context, db *gorm.DB, query interface{},
 	return err
 }
 
+// Find select by query,skip,limit,fields and sort取自query
+func (p *Mysql) Find(ctx context.Context, db *gorm.DB, query *MysqlQuery, data interface{}) (err error) {
+	return p.SelectPlus(ctx, db, query, nil, data, 0, 0, nil, "")
+}
+
+// SelectPage select by page,page starts from 1,return total count
+func (p *Mysql) SelectPage(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}, data interface{}, page int, size int, fields []string, sort string) (total int, err error) {
+
+	total, err = p.Count(ctx, db, query, queryArgs)
+
+	if err != nil || total == 0 {
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	skip := (page - 1) * size
+
+	if size > 0 && skip >= total {
+		return
+	}
+
+	err = p.SelectPlus(ctx, db, query, queryArgs, data, skip, size, fields, sort)
+
+	return
+}
+
+// SelectCursor select by cursor column(eg. id),cursor is nil for first page,desc means column < cursor,
+// return next cursor(value of column of last row) and whether has more,column不在fields中时会追加
+func (p *Mysql) SelectCursor(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}, data interface{}, column string, cursor interface{}, size int, desc bool, fields []string) (next interface{}, hasMore bool, err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return
+	}
+	span, ctx := p.ZipkinNewSpan(ctx, "selectcursor")
+	if span != nil {
+		defer span.Finish()
+	}
+
+	//column会拼接到sql中
+	if !mysqlColumnRegexp.MatchString(column) {
+		err = p.processError(span, fmt.Errorf("cursor column %s is invalid", column), pconst.ERROR_MYSQL_QUERY, "select cursor column error")
+		return
+	}
+
+	if db == nil {
+		db, err = p.GetReadOrm(ctx)
+
+		if err != nil {
+			return
+		}
+	}
+
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, data)
+	if err != nil {
+		err = p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "select cursor query error")
+		return
+	}
+
+	if q, ok := query.(*MysqlQuery); ok && len(fields) == 0 {
+		fields = q.fields
+	}
+
+	op, order := ">", "asc"
+	if desc {
+		op, order = "<", "desc"
+	}
+
+	if cursor != nil {
+		db = db.Where(fmt.Sprintf("%s %s ?", column, op), cursor)
+	}
+	if len(fields) > 0 {
+		db = db.Select(mysqlCursorFields(fields, column))
+	}
+	if size > 0 {
+		db = db.Limit(size + 1)
+	}
+
+	errFind := db.Order(fmt.Sprintf("%s %s", column, order)).Find(data).Error
+
+	if errFind != nil {
+		if errFind.Error() != "record not found" {
+			err = p.processError(span, errFind, pconst.ERROR_MYSQL_SELECT, "select cursor data error")
+		}
+		return
+	}
+
+	last, hasMore := pageTrim(data, size)
+
+	if last != nil {
+		//t.id按id读取
+		name := column[strings.LastIndex(column, ".")+1:]
+
+		field, ok := db.NewScope(last).FieldByName(name)
+		if !ok {
+			err = p.processError(span, fmt.Errorf("cursor column %s not found in data", column), pconst.ERROR_MYSQL_SELECT, "select cursor next error")
+			next, hasMore = nil, false
+			return
+		}
+		next = field.Field.Interface()
+	}
+	return
+}
+
+//mysqlCursorFields fields中没有cursor列时追加,否则读不到下一页的cursor
+func mysqlCursorFields(fields []string, column string) []string {
+	name := column[strings.LastIndex(column, ".")+1:]
+
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+
+		if field == "*" || field == column || field == name || strings.HasSuffix(field, ".*") {
+			return fields
+		}
+	}
+
+	return append(fields[:len(fields):len(fields)], column)
+}
+
 // Update
 func (p *Mysql) Update(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}, sets map[string]interface{}) (rows int64, err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "update")
 	if span != nil {
 		defer span.Finish()
 	}
 
+	p.markWrite(ctx)
+
 	if db == nil {
 		db, err = p.GetWriteOrm(ctx)
 
@@ -282,7 +434,18 @@ func (p *Mysql) Update(ctx context.Context, db *gorm.DB, query interface{}, quer
 		//defer db.Close()
 	}
 
-	dbUpdate := db.Table(p.TableName).Where(query, queryArgs...).Updates(sets)
+	if err = mysqlQueryWriteCheck(query); err != nil {
+		err = p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "update query error")
+		return
+	}
+
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, nil)
+	if err != nil {
+		err = p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "update query error")
+		return
+	}
+
+	dbUpdate := db.Updates(sets)
 
 	err = dbUpdate.Error
 	if err != nil {
@@ -296,12 +459,18 @@ func (p *Mysql) Update(ctx context.Context, db *gorm.DB, query interface{}, quer
 
 // Delete
 func (p *Mysql) Delete(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}) (err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return err
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "delete")
 	if span != nil {
 		defer span.Finish()
 	}
 
+	p.markWrite(ctx)
+
 	if db == nil {
 		db, err = p.GetWriteOrm(ctx)
 
@@ -312,7 +481,16 @@ func (p *Mysql) Delete(ctx context.Context, db *gorm.DB, query interface{}, quer
 		//defer db.Close()
 	}
 
-	errDel := db.Table(p.TableName).Where(query, queryArgs...).Delete(nil).Error
+	if err = mysqlQueryWriteCheck(query); err != nil {
+		return p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "delete query error")
+	}
+
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, nil)
+	if err != nil {
+		return p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "delete query error")
+	}
+
+	errDel := db.Delete(nil).Error
 	if errDel != nil {
 		err = p.processError(span, errDel, pconst.ERROR_MYSQL_DELETE, "delete data error")
 
@@ -322,6 +500,10 @@ func (p *Mysql) Delete(ctx context.Context, db *gorm.DB, query interface{}, quer
 
 // First
 func (p *Mysql) First(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}, data IModelMysql, sort string) (err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return err
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "first")
 	if span != nil {
@@ -338,7 +520,14 @@ func (p *Mysql) First(ctx context.Context, db *gorm.DB, query interface{}, query
 		//defer db.Close()
 	}
 
-	db = db.Table(p.TableName).Where(query, queryArgs...)
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, data)
+	if err != nil {
+		return p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "first query error")
+	}
+
+	if q, ok := query.(*MysqlQuery); ok && sort == "" {
+		sort = q.sort()
+	}
 
 	var errFind error
 
@@ -356,6 +545,10 @@ func (p *Mysql) First(ctx context.Context, db *gorm.DB, query interface{}, query
 
 // Count
 func (p *Mysql) Count(ctx context.Context, db *gorm.DB, query interface{}, queryArgs []interface{}) (count int, err error) {
+	p, err = p.shardRoute(ctx, query)
+	if err != nil {
+		return
+	}
 
 	span, ctx := p.ZipkinNewSpan(ctx, "count")
 	if span != nil {
@@ -372,7 +565,13 @@ func (p *Mysql) Count(ctx context.Context, db *gorm.DB, query interface{}, query
 		//defer db.Close()
 	}
 
-	errCount := db.Table(p.TableName).Where(query, queryArgs...).Count(&count).Error
+	db, err = mysqlQueryWhere(mysqlSpanSet(db.Table(p.TableName), span), query, queryArgs, nil)
+	if err != nil {
+		err = p.processError(span, err, pconst.ERROR_MYSQL_QUERY, "count query error")
+		return
+	}
+
+	errCount := db.Count(&count).Error
 
 	if errCount != nil {
 		err = p.processError(span, errCount, pconst.ERROR_MYSQL_COUNT, "count data error")
@@ -382,10 +581,17 @@ func (p *Mysql) Count(ctx context.Context, db *gorm.DB, query interface{}, query
 
 //Invoke
 func (p *Mysql) Invoke(ctx context.Context, conn *gorm.DB, op string, write bool, fun func(*gorm.DB) error) (err error) {
+	p, err = p.shardRoute(ctx, nil)
+	if err != nil {
+		return err
+	}
 	span, ctx := p.ZipkinNewSpan(ctx, op)
 	if span != nil {
 		defer span.Finish()
 	}
+	if write {
+		p.markWrite(ctx)
+	}
 	if conn == nil {
 		if write {
 			conn, err = p.GetWriteOrm(ctx)
@@ -397,7 +603,7 @@ func (p *Mysql) Invoke(ctx context.Context, conn *gorm.DB, op string, write bool
 			return err
 		}
 	}
-	conn = conn.Table(p.TableName)
+	conn = mysqlSpanSet(conn.Table(p.TableName), span)
 	err = fun(conn)
 	if err != nil {
 		if err.Error() == "record not found" {
@@ -418,13 +624,14 @@ func (p *Mysql) processError(span opentracing.Span, err error, code int, formatt
 	log.Errorf("table :%s, %s", p.TableName, fmt.Sprintf(formatter, a...))
 
 	if span != nil {
+		terr := terror.NewFromError(err)
+		terr.Code = code
+
 		ext.Error.Set(span, true)
-		span.SetTag("err", err)
+		span.SetTag("err", terr)
 	}
 
-	terr := terror.New(code)
-
-	return terr
+	return terror.New(code)
 }
 
 func (p *Mysql) GenQueryInfo(query string, args ...interface{}) (string, []interface{}) {
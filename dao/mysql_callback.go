@@ -0,0 +1,168 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"github.com/jinzhu/gorm"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"sync"
+	"time"
+)
+
+const (
+	mysqlSpanKey  = "tgo:span"
+	mysqlStartKey = "tgo:start"
+	mysqlDbKey    = "tgo:db"
+)
+
+var mysqlCallbackOnce sync.Once
+
+//mysqlSpanSet span of dao operation,sql执行后在callback中记录到span
+func mysqlSpanSet(db *gorm.DB, span opentracing.Span) *gorm.DB {
+	if span == nil {
+		return db
+	}
+	return db.Set(mysqlSpanKey, span)
+}
+
+//mysqlCallbackRegister 在gorm的create,query,update,delete,row_query前后记录sql,行数和耗时;
+//旧版本gorm的callback是所有db共用的,只注册一次,db名通过Set(mysqlDbKey)保存在各个db上,不放在闭包里
+func mysqlCallbackRegister(db *gorm.DB) {
+	mysqlCallbackOnce.Do(func() {
+		callback := db.Callback()
+
+		callback.Create().Before("gorm:create").Register("tgo:before_create", mysqlCallbackBefore)
+		callback.Create().After("gorm:create").Register("tgo:after_create", mysqlCallbackAfter)
+		callback.Query().Before("gorm:query").Register("tgo:before_query", mysqlCallbackBefore)
+		callback.Query().After("gorm:query").Register("tgo:after_query", mysqlCallbackAfter)
+		callback.Update().Before("gorm:update").Register("tgo:before_update", mysqlCallbackBefore)
+		callback.Update().After("gorm:update").Register("tgo:after_update", mysqlCallbackAfter)
+		callback.Delete().Before("gorm:delete").Register("tgo:before_delete", mysqlCallbackBefore)
+		callback.Delete().After("gorm:delete").Register("tgo:after_delete", mysqlCallbackAfter)
+		callback.RowQuery().Before("gorm:row_query").Register("tgo:before_row_query", mysqlCallbackBefore)
+		callback.RowQuery().After("gorm:row_query").Register("tgo:after_row_query", mysqlCallbackAfter)
+	})
+}
+
+func mysqlCallbackBefore(scope *gorm.Scope) {
+	scope.InstanceSet(mysqlStartKey, time.Now())
+}
+
+//mysqlCallbackAfter sql只记录带?的语句,参数不记录,拼接在sql中的常量替换为?
+func mysqlCallbackAfter(scope *gorm.Scope) {
+	value, ok := scope.InstanceGet(mysqlStartKey)
+
+	if !ok || scope.SQL == "" {
+		return
+	}
+
+	statement := mysqlSQLMask(scope.SQL)
+
+	duration := time.Since(value.(time.Time))
+	rows := scope.DB().RowsAffected
+
+	var dbName string
+
+	if value, ok := scope.Get(mysqlDbKey); ok {
+		dbName, _ = value.(string)
+	}
+
+	var span opentracing.Span
+
+	if value, ok := scope.Get(mysqlSpanKey); ok {
+		span, _ = value.(opentracing.Span)
+	}
+
+	if span != nil {
+		ext.DBType.Set(span, "mysql")
+		ext.DBInstance.Set(span, dbName)
+		ext.DBStatement.Set(span, statement)
+		span.SetTag("db.rows", rows)
+		span.SetTag("db.duration_ms", duration.Seconds()*1000)
+	}
+
+	if dbName == "" {
+		return
+	}
+
+	slow := config.MysqlGet(dbName).Conn.Slow
+
+	if slow > 0 && duration >= time.Duration(slow)*time.Millisecond {
+		var traceId string
+
+		if span != nil {
+			traceId = tracing.TraceId(opentracing.ContextWithSpan(context.Background(), span))
+		}
+
+		log.Logf(log.LevelWarn, "mysql slow query db:%s,duration:%dms,rows:%d,trace_id:%s,sql:%s,args:%d",
+			dbName, duration/time.Millisecond, rows, traceId, statement, len(scope.SQLVars))
+	}
+}
+
+//mysqlSQLMask 把字符串和数字常量替换为?,如Where("name = 'a'")拼接的值,`包起来的列名和表名不变
+func mysqlSQLMask(sql string) string {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '`':
+			j := i + 1
+			for j < len(sql) && sql[j] != '`' {
+				j++
+			}
+			if j < len(sql) {
+				j++
+			}
+			buf.WriteString(sql[i:j])
+			i = j
+		case c == '\'' || c == '"':
+			//反斜杠转义和''转义
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == '\\' {
+					j += 2
+					continue
+				}
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			buf.WriteByte('?')
+			i = j + 1
+		case mysqlIsIdent(c):
+			j := i
+			for j < len(sql) && mysqlIsIdent(sql[j]) {
+				j++
+			}
+			//数字开头的为常量,如1,1.5,0x1f,1e5
+			if c >= '0' && c <= '9' {
+				for j < len(sql) && (mysqlIsIdent(sql[j]) || sql[j] == '.') {
+					j++
+				}
+				buf.WriteByte('?')
+			} else {
+				buf.WriteString(sql[i:j])
+			}
+			i = j
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
+
+func mysqlIsIdent(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,355 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+//MysqlQuery typed query,可以作为dao.Mysql各方法的query参数(queryArgs传nil)
+//
+//	q := p.Q().Model(&Order{}).Eq("uid", uid).In("status", status).OrderByDesc("id").Limit(10)
+type MysqlQuery struct {
+	wheres  []mysqlWhere
+	eqs     map[string]interface{}
+	columns []string
+	fields  []string
+	orders  []string
+	skip    int
+	limit   int
+	model   interface{}
+	err     error
+}
+
+type mysqlWhere struct {
+	query string
+	args  []interface{}
+}
+
+var mysqlModelColumnsCache sync.Map
+
+//Q new query
+func (p *Mysql) Q() *MysqlQuery {
+	return &MysqlQuery{eqs: make(map[string]interface{})}
+}
+
+//Model model used to check column names,不设置时使用方法的data或model
+func (q *MysqlQuery) Model(model interface{}) *MysqlQuery {
+	q.model = model
+	return q
+}
+
+//Where raw condition,如Where("uid = ? and status > ?", uid, status),列名不做检查
+func (q *MysqlQuery) Where(query string, args ...interface{}) *MysqlQuery {
+	q.wheres = append(q.wheres, mysqlWhere{query: query, args: args})
+	return q
+}
+
+//Eq column = value,也用于分表路由,value为nil时为column is null
+func (q *MysqlQuery) Eq(column string, value interface{}) *MysqlQuery {
+	if mysqlIsNil(value) {
+		return q.null(column, "is null")
+	}
+	q.eqs[column] = value
+	return q.cmp(column, "=", value)
+}
+
+//Ne column <> value,value为nil时为column is not null
+func (q *MysqlQuery) Ne(column string, value interface{}) *MysqlQuery {
+	if mysqlIsNil(value) {
+		return q.null(column, "is not null")
+	}
+	return q.cmp(column, "<>", value)
+}
+
+//Gt column > value
+func (q *MysqlQuery) Gt(column string, value interface{}) *MysqlQuery {
+	return q.cmp(column, ">", value)
+}
+
+//Gte column >= value
+func (q *MysqlQuery) Gte(column string, value interface{}) *MysqlQuery {
+	return q.cmp(column, ">=", value)
+}
+
+//Lt column < value
+func (q *MysqlQuery) Lt(column string, value interface{}) *MysqlQuery {
+	return q.cmp(column, "<", value)
+}
+
+//Lte column <= value
+func (q *MysqlQuery) Lte(column string, value interface{}) *MysqlQuery {
+	return q.cmp(column, "<=", value)
+}
+
+//Like column like value
+func (q *MysqlQuery) Like(column string, value string) *MysqlQuery {
+	return q.cmp(column, "like", value)
+}
+
+//In column in values,values为slice
+func (q *MysqlQuery) In(column string, values interface{}) *MysqlQuery {
+	return q.in(column, "in", values)
+}
+
+//NotIn column not in values,values为slice
+func (q *MysqlQuery) NotIn(column string, values interface{}) *MysqlQuery {
+	return q.in(column, "not in", values)
+}
+
+//Select fields,默认所有字段
+func (q *MysqlQuery) Select(fields ...string) *MysqlQuery {
+	for _, field := range fields {
+		//表达式(如count(*) as n)不检查
+		if mysqlColumnRegexp.MatchString(field) {
+			q.column(field)
+		}
+	}
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+//OrderBy column asc
+func (q *MysqlQuery) OrderBy(column string) *MysqlQuery {
+	q.column(column)
+	q.orders = append(q.orders, column+" asc")
+	return q
+}
+
+//OrderByDesc column desc
+func (q *MysqlQuery) OrderByDesc(column string) *MysqlQuery {
+	q.column(column)
+	q.orders = append(q.orders, column+" desc")
+	return q
+}
+
+//Skip offset
+func (q *MysqlQuery) Skip(skip int) *MysqlQuery {
+	if skip < 0 {
+		q.fail("skip %d is negative", skip)
+	}
+	q.skip = skip
+	return q
+}
+
+//Limit limit,0为不限制
+func (q *MysqlQuery) Limit(limit int) *MysqlQuery {
+	if limit < 0 {
+		q.fail("limit %d is negative", limit)
+	}
+	q.limit = limit
+	return q
+}
+
+//Err first error of building
+func (q *MysqlQuery) Err() error {
+	return q.err
+}
+
+func (q *MysqlQuery) cmp(column string, op string, value interface{}) *MysqlQuery {
+	q.column(column)
+	q.wheres = append(q.wheres, mysqlWhere{query: fmt.Sprintf("%s %s ?", column, op), args: []interface{}{value}})
+	return q
+}
+
+//null = null不成立,nil需要用is null
+func (q *MysqlQuery) null(column string, op string) *MysqlQuery {
+	q.column(column)
+	q.wheres = append(q.wheres, mysqlWhere{query: fmt.Sprintf("%s %s", column, op)})
+	return q
+}
+
+func (q *MysqlQuery) in(column string, op string, values interface{}) *MysqlQuery {
+	ref := reflect.ValueOf(values)
+
+	if ref.Kind() != reflect.Slice && ref.Kind() != reflect.Array {
+		q.fail("values of %s should be slice", column)
+		return q
+	}
+	q.column(column)
+
+	//gorm把空slice转为(NULL),not in (NULL)查不到任何数据
+	if ref.Len() == 0 {
+		if op == "in" {
+			q.wheres = append(q.wheres, mysqlWhere{query: "1 = 0"})
+		}
+		return q
+	}
+	q.wheres = append(q.wheres, mysqlWhere{query: fmt.Sprintf("%s %s (?)", column, op), args: []interface{}{values}})
+	return q
+}
+
+func (q *MysqlQuery) column(column string) {
+	if !mysqlColumnRegexp.MatchString(column) {
+		q.fail("column %s is invalid", column)
+		return
+	}
+	q.columns = append(q.columns, column)
+}
+
+func (q *MysqlQuery) fail(format string, a ...interface{}) {
+	if q.err == nil {
+		q.err = fmt.Errorf(format, a...)
+	}
+}
+
+//check columns against model
+func (q *MysqlQuery) check(model interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	//没有设置Model时只用IModelMysql检查,data可能只是部分字段的struct
+	if q.model != nil {
+		model = q.model
+	} else if !mysqlIsModel(model) {
+		return nil
+	}
+
+	columns := mysqlModelColumns(model)
+
+	if columns == nil {
+		return nil
+	}
+
+	for _, column := range q.columns {
+		if i := strings.LastIndex(column, "."); i >= 0 {
+			column = column[i+1:]
+		}
+		if !columns[column] {
+			return fmt.Errorf("column %s not found in %T", column, model)
+		}
+	}
+	return nil
+}
+
+//apply conditions to db
+func (q *MysqlQuery) apply(db *gorm.DB) *gorm.DB {
+	for _, where := range q.wheres {
+		db = db.Where(where.query, where.args...)
+	}
+	return db
+}
+
+func (q *MysqlQuery) sort() string {
+	return strings.Join(q.orders, ",")
+}
+
+//mysqlQueryDefaults positional args优先,为零值时取query的
+func mysqlQueryDefaults(q *MysqlQuery, fields []string, skip int, limit int, sort string) ([]string, int, int, string) {
+	if len(fields) == 0 {
+		fields = q.fields
+	}
+	if skip == 0 {
+		skip = q.skip
+	}
+	if limit == 0 {
+		limit = q.limit
+	}
+	if sort == "" {
+		sort = q.sort()
+	}
+	return fields, skip, limit, sort
+}
+
+//mysqlQueryWhere apply query to db,query为*MysqlQuery时检查列名
+func mysqlQueryWhere(db *gorm.DB, query interface{}, queryArgs []interface{}, model interface{}) (*gorm.DB, error) {
+	q, ok := query.(*MysqlQuery)
+
+	if !ok {
+		return db.Where(query, queryArgs...), nil
+	}
+
+	if err := q.check(model); err != nil {
+		return nil, err
+	}
+	return q.apply(db), nil
+}
+
+//mysqlQueryWriteCheck update,delete的MysqlQuery必须有条件,如空的NotIn不生成条件,避免改动全表
+func mysqlQueryWriteCheck(query interface{}) error {
+	if q, ok := query.(*MysqlQuery); ok && q.err == nil && len(q.wheres) == 0 {
+		return fmt.Errorf("query without condition")
+	}
+	return nil
+}
+
+//mysqlIsNil nil或nil指针
+func mysqlIsNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	ref := reflect.ValueOf(value)
+	return ref.Kind() == reflect.Ptr && ref.IsNil()
+}
+
+//mysqlIsModel whether model(或slice的元素)是IModelMysql
+func mysqlIsModel(model interface{}) bool {
+	if model == nil {
+		return false
+	}
+
+	typ := reflect.TypeOf(model)
+
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		if typ.Implements(reflect.TypeOf((*IModelMysql)(nil)).Elem()) {
+			return true
+		}
+		typ = typ.Elem()
+	}
+	return reflect.PtrTo(typ).Implements(reflect.TypeOf((*IModelMysql)(nil)).Elem())
+}
+
+//mysqlModelColumns column names of model,包括字段名和gorm列名,不是struct返回nil
+func mysqlModelColumns(model interface{}) map[string]bool {
+	if model == nil {
+		return nil
+	}
+
+	typ := reflect.TypeOf(model)
+
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if columns, ok := mysqlModelColumnsCache.Load(typ); ok {
+		return columns.(map[string]bool)
+	}
+
+	columns := make(map[string]bool)
+
+	mysqlModelColumnsAdd(typ, columns)
+
+	mysqlModelColumnsCache.Store(typ, columns)
+
+	return columns
+}
+
+func mysqlModelColumnsAdd(typ reflect.Type, columns map[string]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			mysqlModelColumnsAdd(field.Type, columns)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		columns[field.Name] = true
+		columns[gorm.ToDBName(field.Name)] = true
+
+		//gorm:"column:xxx"
+		for _, setting := range strings.Split(field.Tag.Get("gorm"), ";") {
+			if kv := strings.SplitN(setting, ":", 2); len(kv) == 2 && strings.ToUpper(strings.TrimSpace(kv[0])) == "COLUMN" {
+				columns[strings.TrimSpace(kv[1])] = true
+			}
+		}
+	}
+}
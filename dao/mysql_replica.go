@@ -0,0 +1,224 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//mysqlReplica 读库,db为nil表示还未连上
+type mysqlReplica struct {
+	mux    sync.RWMutex
+	db     *gorm.DB
+	up     bool
+	weight int
+	conf   config.MysqlBase
+}
+
+var (
+	mysqlRand    = rand.New(rand.NewSource(time.Now().UnixNano()))
+	mysqlRandMux sync.Mutex
+)
+
+func newMysqlReplica(conf config.MysqlConf, base config.MysqlBase) *mysqlReplica {
+	r := &mysqlReplica{weight: base.Weight, conf: base}
+
+	if r.weight <= 0 {
+		r.weight = 1
+	}
+
+	d, err := initDb(conf, base)
+
+	if err != nil {
+		log.Errorf("mysql read init failed:%+v", err)
+	} else {
+		r.db = d
+		r.up = true
+	}
+
+	return r
+}
+
+//get db and whether replica is available
+func (r *mysqlReplica) get() (*gorm.DB, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.db, r.db != nil && r.up
+}
+
+func (r *mysqlReplica) set(db *gorm.DB, up bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.db = db
+	r.up = up
+}
+
+func (r *mysqlReplica) name() string {
+	return fmt.Sprintf("%s:%d", r.conf.Address, r.conf.Port)
+}
+
+//mysqlReplicaPick 按权重随机选择可用的读库,都不可用返回nil
+func mysqlReplicaPick(replicas []*mysqlReplica) *gorm.DB {
+	var (
+		total int
+		dbs   []*gorm.DB
+		ws    []int
+	)
+
+	for _, r := range replicas {
+		if d, ok := r.get(); ok {
+			total += r.weight
+			dbs = append(dbs, d)
+			ws = append(ws, r.weight)
+		}
+	}
+
+	if len(dbs) == 0 {
+		return nil
+	}
+	if len(dbs) == 1 {
+		return dbs[0]
+	}
+
+	mysqlRandMux.Lock()
+	n := mysqlRand.Intn(total)
+	mysqlRandMux.Unlock()
+
+	for i, w := range ws {
+		if n < w {
+			return dbs[i]
+		}
+		n -= w
+	}
+
+	return dbs[len(dbs)-1]
+}
+
+//mysqlReplicaCheckStart 定时检查读库
+func mysqlReplicaCheckStart(conf config.MysqlConf, replicas []*mysqlReplica) {
+	check := conf.Conn.Check
+
+	if check.Interval <= 0 || len(replicas) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(check.Interval) * time.Millisecond)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for _, r := range replicas {
+				mysqlReplicaCheck(conf, r)
+			}
+		}
+	}()
+}
+
+//mysqlReplicaCheck 未连上的重连,ping和主从延迟检查,状态变化时打日志
+func mysqlReplicaCheck(conf config.MysqlConf, r *mysqlReplica) {
+	d, upBefore := r.get()
+
+	if d == nil {
+		var err error
+		d, err = initDb(conf, r.conf)
+
+		if err != nil {
+			return
+		}
+	}
+
+	timeout := conf.Conn.Check.Timeout
+
+	if timeout <= 0 {
+		timeout = 1000
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+
+	err := d.DB().PingContext(ctx)
+
+	if err == nil && conf.Conn.Check.MaxLag > 0 {
+		var lag int
+		lag, err = mysqlReplicaLag(ctx, d.DB())
+
+		if err == nil && lag > conf.Conn.Check.MaxLag {
+			err = fmt.Errorf("lag %ds exceeds %ds", lag, conf.Conn.Check.MaxLag)
+		}
+	}
+
+	up := err == nil
+
+	r.set(d, up)
+
+	if up != upBefore {
+		if up {
+			log.Logf(log.LevelInfo, "mysql %s reader %s is up", conf.Db, r.name())
+		} else {
+			log.Errorf("mysql %s reader %s is down:%s", conf.Db, r.name(), err.Error())
+		}
+	}
+}
+
+//mysqlReplicaLag Seconds_Behind_Master,为NULL说明复制已停止;
+//MySQL 8.4去掉了SHOW SLAVE STATUS,失败时使用SHOW REPLICA STATUS
+func mysqlReplicaLag(ctx context.Context, db *sql.DB) (int, error) {
+	lag, err := mysqlReplicaLagQuery(ctx, db, "SHOW SLAVE STATUS")
+
+	if err != nil && ctx.Err() == nil {
+		if lagReplica, errReplica := mysqlReplicaLagQuery(ctx, db, "SHOW REPLICA STATUS"); errReplica == nil {
+			return lagReplica, nil
+		}
+	}
+	return lag, err
+}
+
+func mysqlReplicaLagQuery(ctx context.Context, db *sql.DB, query string) (int, error) {
+	rows, err := db.QueryContext(ctx, query)
+
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		return 0, fmt.Errorf("not a replica")
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err = rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, c := range columns {
+		if c == "Seconds_Behind_Master" || c == "Seconds_Behind_Source" {
+			if values[i] == nil {
+				return 0, fmt.Errorf("replication is stopped")
+			}
+			return strconv.Atoi(string(values[i]))
+		}
+	}
+
+	return 0, fmt.Errorf("Seconds_Behind_Master not found")
+}
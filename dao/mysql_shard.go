@@ -0,0 +1,357 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"golang.org/x/sync/errgroup"
+	"hash/crc32"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+type mysqlShardKey struct{}
+
+//WithShardKey value of shard key(MysqlShard.Key) used by dao.Mysql in ctx,只对Key相同的分片表生效,
+//query和model中有值时使用其中的值,和ctx的值不在同一分片时报错
+func WithShardKey(ctx context.Context, key string, value interface{}) context.Context {
+	values := make(map[string]interface{})
+
+	if old, ok := ctx.Value(mysqlShardKey{}).(map[string]interface{}); ok {
+		for k, v := range old {
+			values[k] = v
+		}
+	}
+	values[key] = value
+
+	return context.WithValue(ctx, mysqlShardKey{}, values)
+}
+
+//mysqlShardCtxValue value of shard key in ctx,nil if not exists
+func mysqlShardCtxValue(ctx context.Context, key string) interface{} {
+	values, _ := ctx.Value(mysqlShardKey{}).(map[string]interface{})
+	return values[key]
+}
+
+//shardGet sharding rule of table,nil means not sharded
+func (p *Mysql) shardGet() *config.MysqlShard {
+	shards := config.MysqlGet(p.getDbName()).Shards
+
+	for i := range shards {
+		if shards[i].Table == p.TableName {
+			return &shards[i]
+		}
+	}
+	return nil
+}
+
+//IsSharded whether table has sharding rule
+func (p *Mysql) IsSharded() bool {
+	return p.shardGet() != nil
+}
+
+//Shard physical dao of shard key value,table没有分片规则时返回自己
+func (p *Mysql) Shard(value interface{}) (*Mysql, error) {
+	shard := p.shardGet()
+
+	if shard == nil {
+		return p, nil
+	}
+
+	index, err := mysqlShardIndex(shard, value)
+
+	if err != nil {
+		log.Errorf("table :%s, shard key %s error:%s", p.TableName, shard.Key, err.Error())
+		return nil, terror.New(pconst.ERROR_MYSQL_SHARD)
+	}
+
+	return p.shardAt(shard, index), nil
+}
+
+//Shards all physical daos,用于显式的scatter-gather
+func (p *Mysql) Shards() []*Mysql {
+	shard := p.shardGet()
+
+	if shard == nil {
+		return []*Mysql{p}
+	}
+
+	shards := make([]*Mysql, shard.Tables)
+
+	for i := range shards {
+		shards[i] = p.shardAt(shard, i)
+	}
+	return shards
+}
+
+func (p *Mysql) shardAt(shard *config.MysqlShard, index int) *Mysql {
+	dbName := p.getDbName()
+
+	if len(shard.Dbs) > 0 {
+		perDb := (shard.Tables + len(shard.Dbs) - 1) / len(shard.Dbs)
+		dbName = shard.Dbs[index/perDb]
+	}
+
+	width := len(strconv.Itoa(shard.Tables - 1))
+	if width < 2 {
+		width = 2
+	}
+
+	return &Mysql{DbName: dbName, TableName: fmt.Sprintf("%s_%0*d", shard.Table, width, index)}
+}
+
+//shardRoute physical dao,shard key优先从query(map,struct或MysqlQuery.Eq)或model中获取,没有时使用ctx中的,
+//两者都有但不在同一分片时报错,避免写到ctx(如事务)之外的分片
+func (p *Mysql) shardRoute(ctx context.Context, source interface{}) (*Mysql, error) {
+	shard := p.shardGet()
+
+	if shard == nil {
+		return p, nil
+	}
+
+	value := mysqlShardValue(source, shard.Key)
+	ctxValue := mysqlShardCtxValue(ctx, shard.Key)
+
+	if value == nil {
+		value = ctxValue
+	}
+
+	if value == nil {
+		log.Errorf("table :%s, shard key %s not found,use WithShardKey or ShardEach", p.TableName, shard.Key)
+		return nil, terror.New(pconst.ERROR_MYSQL_SHARD)
+	}
+
+	if ctxValue != nil {
+		index, err := mysqlShardIndex(shard, value)
+		ctxIndex, errCtx := mysqlShardIndex(shard, ctxValue)
+
+		if err == nil && errCtx == nil && index != ctxIndex {
+			log.Errorf("table :%s, shard key %s value %v and ctx value %v are in different shards", p.TableName, shard.Key, value, ctxValue)
+			return nil, terror.New(pconst.ERROR_MYSQL_SHARD)
+		}
+	}
+
+	return p.Shard(value)
+}
+
+//ShardEach call fn with every physical dao concurrently,没有shard key的查询需要显式使用,
+//同时执行的分片数为Concurrency,fn返回error时取消ctx,不再执行剩下的分片
+func (p *Mysql) ShardEach(ctx context.Context, fn func(ctx context.Context, shard *Mysql) error) error {
+	shards := p.Shards()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	sem := make(chan struct{}, p.shardConcurrency(len(shards)))
+
+	for _, shard := range shards {
+		select {
+		case sem <- struct{}{}:
+		case <-groupCtx.Done():
+			return mysqlShardWait(ctx, group)
+		}
+
+		shard := shard
+
+		group.Go(func() error {
+			defer func() {
+				<-sem
+			}()
+
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
+			return fn(groupCtx, shard)
+		})
+	}
+	return mysqlShardWait(ctx, group)
+}
+
+//mysqlShardWait first error of fn,没有时返回ctx被取消的错误
+func mysqlShardWait(ctx context.Context, group *errgroup.Group) error {
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+//shardConcurrency concurrency of ShardEach,默认不超过连接池大小
+func (p *Mysql) shardConcurrency(total int) int {
+	n := config.MysqlGet(p.getDbName()).Conn.Pool.Max
+
+	if shard := p.shardGet(); shard != nil && shard.Concurrency > 0 {
+		n = shard.Concurrency
+	}
+	if n <= 0 || n > total {
+		n = total
+	}
+	if n <= 0 {
+		n = 1
+	}
+	return n
+}
+
+//ShardSelect select from all shards,data为slice指针,结果按分片顺序拼接,不做全局排序和分页
+func (p *Mysql) ShardSelect(ctx context.Context, query interface{}, queryArgs []interface{}, data interface{}, fields []string) error {
+	refData := reflect.ValueOf(data)
+
+	if refData.Kind() != reflect.Ptr || refData.Elem().Kind() != reflect.Slice {
+		log.Errorf("table :%s, shard select data should be pointer of slice", p.TableName)
+		return terror.New(pconst.ERROR_MYSQL_SELECT)
+	}
+
+	var mux sync.Mutex
+
+	return p.ShardEach(ctx, func(ctx context.Context, shard *Mysql) error {
+		part := reflect.New(refData.Elem().Type())
+
+		if err := shard.SelectPlus(ctx, nil, query, queryArgs, part.Interface(), 0, 0, fields, ""); err != nil {
+			return err
+		}
+
+		mux.Lock()
+		refData.Elem().Set(reflect.AppendSlice(refData.Elem(), part.Elem()))
+		mux.Unlock()
+
+		return nil
+	})
+}
+
+//ShardCount count of all shards
+func (p *Mysql) ShardCount(ctx context.Context, query interface{}, queryArgs []interface{}) (total int, err error) {
+	var mux sync.Mutex
+
+	err = p.ShardEach(ctx, func(ctx context.Context, shard *Mysql) error {
+		count, err := shard.Count(ctx, nil, query, queryArgs)
+
+		if err != nil {
+			return err
+		}
+
+		mux.Lock()
+		total += count
+		mux.Unlock()
+
+		return nil
+	})
+	return
+}
+
+//mysqlShardIndex table index of value,hash时整数取模,字符串crc32取模
+func mysqlShardIndex(shard *config.MysqlShard, value interface{}) (int, error) {
+	ref := reflect.ValueOf(value)
+
+	for ref.Kind() == reflect.Ptr {
+		if ref.IsNil() {
+			return 0, fmt.Errorf("value is nil")
+		}
+		ref = ref.Elem()
+	}
+
+	var (
+		n        int64
+		isString bool
+		isUint   bool
+	)
+
+	switch ref.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = ref.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		isUint = true
+	case reflect.String:
+		isString = true
+	default:
+		return 0, fmt.Errorf("unsupported type %s", ref.Type())
+	}
+
+	switch shard.Type {
+	case config.MysqlShardHash:
+		if isString {
+			return int(crc32.ChecksumIEEE([]byte(ref.String())) % uint32(shard.Tables)), nil
+		}
+		if isUint {
+			return int(ref.Uint() % uint64(shard.Tables)), nil
+		}
+		//先取模再取反,-MinInt64会溢出
+		index := n % int64(shard.Tables)
+		if index < 0 {
+			index = -index
+		}
+		return int(index), nil
+	case config.MysqlShardRange:
+		if isString {
+			return 0, fmt.Errorf("range shard key should be integer")
+		}
+		if isUint {
+			if ref.Uint() > math.MaxInt64 {
+				return 0, fmt.Errorf("value %d out of ranges", ref.Uint())
+			}
+			n = int64(ref.Uint())
+		}
+		for i, upper := range shard.Ranges {
+			if n < upper {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("value %d out of ranges", n)
+	}
+
+	return 0, fmt.Errorf("unknown type %s", shard.Type)
+}
+
+//mysqlShardValue value of key from map or struct(字段名或gorm列名)
+func mysqlShardValue(source interface{}, key string) interface{} {
+	if source == nil {
+		return nil
+	}
+
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[key]
+	}
+	if q, ok := source.(*MysqlQuery); ok {
+		return q.eqs[key]
+	}
+
+	ref := reflect.ValueOf(source)
+
+	for ref.Kind() == reflect.Ptr {
+		if ref.IsNil() {
+			return nil
+		}
+		ref = ref.Elem()
+	}
+
+	if ref.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < ref.NumField(); i++ {
+		field := ref.Type().Field(i)
+
+		if !ref.Field(i).CanInterface() {
+			continue
+		}
+		if field.Anonymous {
+			if value := mysqlShardValue(ref.Field(i).Interface(), key); value != nil {
+				return value
+			}
+			continue
+		}
+		//零值当作没有设置,同gorm的struct条件
+		if field.Name == key || gorm.ToDBName(field.Name) == key {
+			value := ref.Field(i).Interface()
+
+			if reflect.DeepEqual(value, reflect.Zero(field.Type).Interface()) {
+				return nil
+			}
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"sync"
+	"time"
+)
+
+type mysqlPrimaryKey struct{}
+
+type mysqlStickyKey struct{}
+
+//mysqlSticky 记录ctx内每个db最后一次写的时间
+type mysqlSticky struct {
+	mux    sync.Mutex
+	writes map[string]time.Time
+}
+
+//mysqlStickyProcess 没有WithSticky的ctx使用进程内的写时间,窗口内该db的所有读都走主库
+var mysqlStickyProcess = &mysqlSticky{writes: make(map[string]time.Time)}
+
+//WithPrimary reads of dao.Mysql in ctx go to writer
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mysqlPrimaryKey{}, true)
+}
+
+//WithSticky read-your-writes only by writes in ctx,ctx内写过的db之后的读走主库,窗口由配置Sticky决定;
+//没有WithSticky或MiddlewareSticky的ctx按进程内最后一次写判断,其他请求的写也会让读走主库
+func WithSticky(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(mysqlStickyKey{}).(*mysqlSticky); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, mysqlStickyKey{}, &mysqlSticky{writes: make(map[string]time.Time)})
+}
+
+//MiddlewareSticky gin middleware,每个请求WithSticky,写多的服务加到engine上,避免读都走主库
+func MiddlewareSticky() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(WithSticky(c.Request.Context()))
+
+		c.Next()
+	}
+}
+
+//mysqlStickyGet sticky of ctx,没有时为进程内的
+func mysqlStickyGet(ctx context.Context) *mysqlSticky {
+	if sticky, ok := ctx.Value(mysqlStickyKey{}).(*mysqlSticky); ok {
+		return sticky
+	}
+	return mysqlStickyProcess
+}
+
+//markWrite record write of db in ctx
+func (p *Mysql) markWrite(ctx context.Context) {
+	sticky := mysqlStickyGet(ctx)
+
+	sticky.mux.Lock()
+	sticky.writes[p.getDbName()] = time.Now()
+	sticky.mux.Unlock()
+}
+
+//readPrimary whether read should go to writer
+func (p *Mysql) readPrimary(ctx context.Context) bool {
+	if primary, ok := ctx.Value(mysqlPrimaryKey{}).(bool); ok && primary {
+		return true
+	}
+
+	sticky := mysqlStickyGet(ctx)
+
+	dbName := p.getDbName()
+
+	sticky.mux.Lock()
+	last, written := sticky.writes[dbName]
+	sticky.mux.Unlock()
+
+	if !written {
+		return false
+	}
+
+	window := config.MysqlGet(dbName).Conn.Sticky
+
+	return time.Since(last) < time.Duration(window)*time.Millisecond
+}
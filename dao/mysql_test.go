@@ -2,7 +2,17 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"github.com/jinzhu/gorm"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"github.com/tonyjt/tgo_v2/tracing/tracetest"
+	"math"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type testModelMysql struct {
@@ -45,6 +55,9 @@ func TestMysql_GetReadOrm(t *testing.T) {
 }
 
 func TestMysql_Insert(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
 	m := testInit()
 
 	s := m1{
@@ -56,6 +69,14 @@ func TestMysql_Insert(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	r.AssertError(t, "mysql:insert:test", err != nil)
+
+	span := r.AssertSpan(t, "mysql:insert:test", map[string]interface{}{"db.type": "mysql", "db.instance": "tgo1", "db.rows": int64(1)})
+
+	if span != nil && span.Tag("db.statement") == nil {
+		t.Error("db.statement should be tagged")
+	}
 }
 
 func TestMysql_Select(t *testing.T) {
@@ -65,7 +86,7 @@ func TestMysql_Select(t *testing.T) {
 
 	var s []m1
 
-	err := m.Select(context.TODO(), nil, condition, &s, 0, 0, []string{}, "")
+	err := m.SelectPlus(context.TODO(), nil, condition, nil, &s, 0, 0, []string{}, "")
 
 	if err != nil {
 		t.Error(err)
@@ -74,6 +95,44 @@ func TestMysql_Select(t *testing.T) {
 	}
 }
 
+func TestMysql_SelectStatementMasked(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
+	m := testInit()
+
+	var s []m1
+
+	if err := m.Select(context.Background(), nil, "name = 'test1' and value > 5", nil, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	span := r.AssertSpan(t, "mysql:select:test", map[string]interface{}{"db.type": "mysql"})
+	if span == nil {
+		return
+	}
+	statement, _ := span.Tag("db.statement").(string)
+
+	if strings.Contains(statement, "test1") || !strings.Contains(statement, "name = ? and value > ?") {
+		t.Errorf("literals should be masked:%s", statement)
+	}
+}
+
+func TestMysqlSQLMask(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM `test` WHERE (name = 'test1') AND (value > 10)": "SELECT * FROM `test` WHERE (name = ?) AND (value > ?)",
+		`SELECT * FROM t2 WHERE name = 'it''s' AND x = "a\"b"`:         "SELECT * FROM t2 WHERE name = ? AND x = ?",
+		"SELECT * FROM t2 WHERE t1.id IN (1, 2.5, 0x1f) LIMIT 10":      "SELECT * FROM t2 WHERE t1.id IN (?, ?, ?) LIMIT ?",
+		"UPDATE `test_01` SET `value` = ? WHERE (name = ?)":            "UPDATE `test_01` SET `value` = ? WHERE (name = ?)",
+	}
+
+	for sql, expected := range cases {
+		if masked := mysqlSQLMask(sql); masked != expected {
+			t.Errorf("mask of %s:%s", sql, masked)
+		}
+	}
+}
+
 func BenchmarkMysql_Select(b *testing.B) {
 
 	b.RunParallel(func(pb *testing.PB) {
@@ -85,7 +144,7 @@ func BenchmarkMysql_Select(b *testing.B) {
 
 			var s []m1
 
-			err := m.Select(context.TODO(), nil, condition, &s, 0, 0, []string{}, "")
+			err := m.SelectPlus(context.TODO(), nil, condition, nil, &s, 0, 0, []string{}, "")
 
 			if err != nil {
 				b.Error(err)
@@ -101,7 +160,7 @@ func TestMysql_Count(t *testing.T) {
 
 	condition := "name = 'test1'"
 
-	count, err := m.Count(context.Background(), nil, condition)
+	count, err := m.Count(context.Background(), nil, condition, nil)
 
 	if err != nil {
 		t.Error(err)
@@ -117,7 +176,7 @@ func TestMysql_First(t *testing.T) {
 
 	var s m1
 
-	err := m.First(context.Background(), nil, condition, &s, "name desc")
+	err := m.First(context.Background(), nil, condition, nil, &s, "name desc")
 
 	if err != nil {
 		t.Error(err)
@@ -156,7 +215,7 @@ func TestMysql_Trans(t *testing.T) {
 
 	set["value"] = 25
 
-	_,err = m.Update(ctx, db, condition, set)
+	_, err = m.Update(ctx, db, condition, nil, set)
 
 	if err != nil {
 		t.Error(err)
@@ -174,7 +233,7 @@ func TestMysql_Update(t *testing.T) {
 
 	set["value"] = 20
 
-	_,err := m.Update(context.Background(), nil, condition, set)
+	_, err := m.Update(context.Background(), nil, condition, nil, set)
 
 	if err != nil {
 		t.Error(err)
@@ -193,3 +252,409 @@ func TestMysql_Delete(t *testing.T) {
 		t.Error(err)
 	}
 }*/
+
+func TestMysql_Transaction(t *testing.T) {
+	m := testInit()
+	ctx := context.Background()
+
+	err := m.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := m.Insert(ctx, tx, &m1{Name: "testtx", Value: 1}); err != nil {
+			return err
+		}
+
+		//savepoint回滚不影响外层事务
+		errInner := m.TransactionPlus(ctx, tx, func(tx *gorm.DB) error {
+			if err := m.Insert(ctx, tx, &m1{Name: "testtxinner", Value: 1}); err != nil {
+				return err
+			}
+			return errors.New("rollback inner")
+		})
+		if errInner == nil {
+			t.Error("inner transaction should fail")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count, _ := m.Count(ctx, nil, "name = ?", []interface{}{"testtxinner"}); count != 0 {
+		t.Errorf("inner insert should be rolled back,count:%d", count)
+	}
+	m.Delete(ctx, nil, "name = ?", []interface{}{"testtx"})
+}
+
+func TestMysql_TransactionContext(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
+	m := testInit()
+
+	err := m.TransactionContext(context.Background(), nil, func(ctx context.Context, tx *gorm.DB) error {
+		//db为nil时使用ctx中的事务
+		if err := m.Insert(ctx, nil, &m1{Name: "testtxctx", Value: 1}); err != nil {
+			return err
+		}
+
+		//ctx中有事务时嵌套的Transaction使用savepoint
+		errInner := m.Transaction(ctx, func(tx *gorm.DB) error {
+			if err := m.Insert(ctx, tx, &m1{Name: "testtxctxinner", Value: 1}); err != nil {
+				return err
+			}
+			return errors.New("rollback inner")
+		})
+		if errInner == nil {
+			t.Error("inner transaction should fail")
+		}
+
+		if count, _ := m.Count(ctx, nil, "name = ?", []interface{}{"testtxctx"}); count != 1 {
+			t.Errorf("read in transaction should see insert,count:%d", count)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if count, _ := m.Count(ctx, nil, "name = ?", []interface{}{"testtxctxinner"}); count != 0 {
+		t.Errorf("inner insert should be rolled back,count:%d", count)
+	}
+	if count, _ := m.Count(WithPrimary(ctx), nil, "name = ?", []interface{}{"testtxctx"}); count != 1 {
+		t.Errorf("outer insert should be committed,count:%d", count)
+	}
+	m.Delete(ctx, nil, "name = ?", []interface{}{"testtxctx"})
+
+	//按finish顺序,外层事务最后结束,insert使用fn的ctx
+	txs, inserts := r.Spans("mysql:transaction:test"), r.Spans("mysql:insert:test")
+	if len(txs) != 2 || len(inserts) != 2 {
+		t.Fatalf("spans error,transaction:%d,insert:%d", len(txs), len(inserts))
+	}
+	tracetest.AssertChild(t, txs[1], txs[0])
+	tracetest.AssertChild(t, txs[1], inserts[0])
+}
+
+func TestMysql_ReadPrimary(t *testing.T) {
+	m := testInit()
+
+	//其他测试的写记录在进程内
+	mysqlStickyProcess.mux.Lock()
+	mysqlStickyProcess.writes = make(map[string]time.Time)
+	mysqlStickyProcess.mux.Unlock()
+
+	if m.readPrimary(context.Background()) {
+		t.Error("read should not go to primary without write")
+	}
+	if !m.readPrimary(WithPrimary(context.Background())) {
+		t.Error("read should go to primary with WithPrimary")
+	}
+
+	ctx := WithSticky(context.Background())
+
+	//没有WithSticky的ctx记录在进程内,不影响WithSticky的ctx
+	m.markWrite(context.Background())
+
+	if !m.readPrimary(context.Background()) {
+		t.Error("read without sticky should go to primary after write")
+	}
+	if m.readPrimary(ctx) {
+		t.Error("read should not go to primary before write")
+	}
+	m.markWrite(ctx)
+
+	if !m.readPrimary(ctx) {
+		t.Error("read should go to primary after write")
+	}
+
+	//超过Sticky窗口后读从库
+	mysqlStickyProcess.mux.Lock()
+	mysqlStickyProcess.writes[m.DbName] = time.Now().Add(-time.Hour)
+	mysqlStickyProcess.mux.Unlock()
+
+	if m.readPrimary(context.Background()) {
+		t.Error("read should not go to primary after sticky window")
+	}
+
+	other := &testModelMysql{Mysql{DbName: "tgo", TableName: "test"}}
+
+	if other.readPrimary(ctx) {
+		t.Error("write of tgo1 should not stick tgo")
+	}
+}
+
+func TestMysqlReplicaPick(t *testing.T) {
+	d1, d2 := &gorm.DB{}, &gorm.DB{}
+
+	replicas := []*mysqlReplica{
+		{db: d1, up: true, weight: 3},
+		{db: d2, up: true, weight: 1},
+		{db: &gorm.DB{}, up: false, weight: 100},
+		{weight: 100},
+	}
+
+	counts := make(map[*gorm.DB]int)
+
+	for i := 0; i < 4000; i++ {
+		counts[mysqlReplicaPick(replicas)]++
+	}
+
+	if len(counts) != 2 || counts[d2] == 0 {
+		t.Errorf("down replicas should not be picked,last should be picked:%v", counts)
+	}
+	if counts[d1] < counts[d2]*2 {
+		t.Errorf("weight not respected,d1:%d,d2:%d", counts[d1], counts[d2])
+	}
+
+	replicas[0].set(d1, false)
+	replicas[1].set(d2, false)
+
+	if mysqlReplicaPick(replicas) != nil {
+		t.Error("should be nil when all replicas are down")
+	}
+}
+
+func TestMysqlShard(t *testing.T) {
+	hash := &config.MysqlShard{Table: "orders", Key: "user_id", Type: config.MysqlShardHash, Tables: 64, Dbs: []string{"tgo", "tgo1"}}
+
+	if index, err := mysqlShardIndex(hash, int64(130)); err != nil || index != 2 {
+		t.Errorf("hash index should be 2:%d,%v", index, err)
+	}
+	if index, err := mysqlShardIndex(hash, int64(-130)); err != nil || index != 2 {
+		t.Errorf("hash index of -130 should be 2:%d,%v", index, err)
+	}
+	if index, err := mysqlShardIndex(hash, int64(math.MinInt64)); err != nil || index < 0 || index >= hash.Tables {
+		t.Errorf("hash index of min int64 out of tables:%d,%v", index, err)
+	}
+	if index, err := mysqlShardIndex(hash, uint64(math.MaxUint64)); err != nil || index != 63 {
+		t.Errorf("hash index of max uint64 should be 63:%d,%v", index, err)
+	}
+	if _, err := mysqlShardIndex(hash, 1.5); err == nil {
+		t.Error("float shard key should fail")
+	}
+
+	m := &Mysql{DbName: "tgo", TableName: "orders"}
+
+	if s := m.shardAt(hash, 2); s.DbName != "tgo" || s.TableName != "orders_02" {
+		t.Errorf("shard 2 should be tgo.orders_02:%s.%s", s.DbName, s.TableName)
+	}
+	if s := m.shardAt(hash, 63); s.DbName != "tgo1" || s.TableName != "orders_63" {
+		t.Errorf("shard 63 should be tgo1.orders_63:%s.%s", s.DbName, s.TableName)
+	}
+
+	ranges := &config.MysqlShard{Table: "orders", Key: "id", Type: config.MysqlShardRange, Tables: 2, Ranges: []int64{100, 200}}
+
+	if index, err := mysqlShardIndex(ranges, 150); err != nil || index != 1 {
+		t.Errorf("range index should be 1:%d,%v", index, err)
+	}
+	if _, err := mysqlShardIndex(ranges, 200); err == nil {
+		t.Error("value out of ranges should fail")
+	}
+
+	type order struct {
+		ModelMysql
+		UserId int64
+	}
+
+	if v := mysqlShardValue(&order{UserId: 7}, "user_id"); v != int64(7) {
+		t.Errorf("shard value from model should be 7:%v", v)
+	}
+	if v := mysqlShardValue(&order{}, "user_id"); v != nil {
+		t.Errorf("zero shard value should be nil:%v", v)
+	}
+	if v := mysqlShardValue(map[string]interface{}{"user_id": 8}, "user_id"); v != 8 {
+		t.Errorf("shard value from map should be 8:%v", v)
+	}
+	if v := mysqlShardValue("user_id = ?", "user_id"); v != nil {
+		t.Errorf("shard value from string should be nil:%v", v)
+	}
+}
+
+func testMysqlShardConfig(shard config.MysqlShard) func() {
+	all := config.MysqlGetAll()
+	conf := all["tgo1"]
+
+	sharded := conf
+	sharded.Shards = []config.MysqlShard{shard}
+	all["tgo1"] = sharded
+
+	return func() {
+		all["tgo1"] = conf
+	}
+}
+
+func TestMysql_ShardRoute(t *testing.T) {
+	defer testMysqlShardConfig(config.MysqlShard{Table: "test", Key: "user_id", Type: config.MysqlShardHash, Tables: 8})()
+
+	m := testInit()
+	ctx := WithShardKey(context.Background(), "user_id", 1)
+
+	if s, err := m.shardRoute(ctx, nil); err != nil || s.TableName != "test_01" {
+		t.Errorf("should route by ctx value:%v,%v", s, err)
+	}
+	if s, err := m.shardRoute(ctx, map[string]interface{}{"user_id": int64(9)}); err != nil || s.TableName != "test_01" {
+		t.Errorf("same shard of ctx value should pass:%v,%v", s, err)
+	}
+	if _, err := m.shardRoute(ctx, map[string]interface{}{"user_id": 2}); err == nil {
+		t.Error("different shard of ctx value should fail")
+	}
+	if s, err := m.shardRoute(context.Background(), map[string]interface{}{"user_id": 2}); err != nil || s.TableName != "test_02" {
+		t.Errorf("should route by query value:%v,%v", s, err)
+	}
+
+	//其他key的值不生效
+	if _, err := m.shardRoute(WithShardKey(context.Background(), "tenant_id", 1), nil); err == nil {
+		t.Error("ctx value of other key should not be used")
+	}
+}
+
+func TestMysql_ShardEach(t *testing.T) {
+	defer testMysqlShardConfig(config.MysqlShard{Table: "test", Key: "name", Type: config.MysqlShardHash, Tables: 8, Concurrency: 2})()
+
+	m := testInit()
+
+	var running, max int32
+
+	err := m.ShardEach(context.Background(), func(ctx context.Context, shard *Mysql) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			old := atomic.LoadInt32(&max)
+			if n <= old || atomic.CompareAndSwapInt32(&max, old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max != 2 {
+		t.Errorf("concurrency should be 2:%d", max)
+	}
+
+	var called int32
+	errShard := errors.New("shard failed")
+
+	err = m.ShardEach(context.Background(), func(ctx context.Context, shard *Mysql) error {
+		if atomic.AddInt32(&called, 1) == 1 {
+			return errShard
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err != errShard {
+		t.Errorf("should return first error:%v", err)
+	}
+	if called > 2 {
+		t.Errorf("shards after error should not be called:%d", called)
+	}
+}
+
+func TestMysql_TransactionShard(t *testing.T) {
+	defer testMysqlShardConfig(config.MysqlShard{Table: "test", Key: "name", Type: config.MysqlShardHash, Tables: 2})()
+
+	m := testInit()
+
+	err := m.Transaction(context.Background(), func(tx *gorm.DB) error {
+		t.Error("fn should not be called without shard key")
+		return nil
+	})
+
+	if te, ok := err.(*terror.TError); !ok || te.Code != pconst.ERROR_MYSQL_SHARD {
+		t.Errorf("transaction without shard key should fail with %d:%v", pconst.ERROR_MYSQL_SHARD, err)
+	}
+}
+
+func TestMysqlQuery(t *testing.T) {
+	m := testInit()
+
+	q := m.Q().Eq("name", "test1").In("value", []int64{10, 20}).OrderByDesc("id").Limit(10)
+
+	if err := q.check(&[]m1{}); err != nil {
+		t.Error(err)
+	}
+	if len(q.wheres) != 2 || q.wheres[1].query != "value in (?)" || q.sort() != "id desc" || q.limit != 10 {
+		t.Errorf("query is wrong:%+v", q)
+	}
+
+	if err := m.Q().Eq("nmae", "test1").check(&m1{}); err == nil {
+		t.Error("unknown column should fail")
+	}
+	if err := m.Q().Eq("nmae", "test1").check(&struct{ Name string }{}); err != nil {
+		t.Errorf("column should not be checked without model:%v", err)
+	}
+	if err := m.Q().In("value", 10).check(nil); err == nil {
+		t.Error("in should require slice")
+	}
+	if q := m.Q().In("value", []int64{}); len(q.wheres) != 1 || q.wheres[0].query != "1 = 0" {
+		t.Errorf("empty in should match nothing:%+v", q.wheres)
+	}
+	if q := m.Q().NotIn("value", []int64{}); len(q.wheres) != 0 {
+		t.Errorf("empty not in should add no condition:%+v", q.wheres)
+	}
+	if q := m.Q().Eq("name", nil).Ne("value", nil); len(q.wheres) != 2 || q.wheres[0].query != "name is null" ||
+		q.wheres[1].query != "value is not null" || len(q.eqs) != 0 {
+		t.Errorf("nil should be is null and is not null:%+v", q.wheres)
+	}
+	if err := mysqlQueryWriteCheck(m.Q().NotIn("value", []int64{})); err == nil {
+		t.Error("write without condition should fail")
+	}
+	if err := mysqlQueryWriteCheck(m.Q().NotIn("value", []int64{}).Eq("name", "test1")); err != nil {
+		t.Errorf("write with condition should pass:%v", err)
+	}
+
+	//空的NotIn不能删除全表
+	if err := m.Delete(context.Background(), nil, m.Q().NotIn("value", []int64{}), nil); err == nil {
+		t.Error("delete without condition should fail")
+	}
+	if err := m.Q().OrderBy("id;drop table test").check(nil); err == nil {
+		t.Error("invalid column should fail")
+	}
+
+	var s []m1
+
+	if err := m.Find(context.Background(), nil, m.Q().Eq("name", "test1").Limit(1), &s); err != nil {
+		t.Error(err)
+	} else if len(s) != 1 {
+		t.Errorf("should find 1 row:%d", len(s))
+	}
+}
+
+func TestMysql_SelectCursorColumn(t *testing.T) {
+	m := testInit()
+
+	var s []m1
+
+	_, _, err := m.SelectCursor(context.Background(), nil, "name = ?", []interface{}{"test1"}, &s, "id;drop table test", nil, 10, false, nil)
+
+	if err == nil {
+		t.Error("invalid cursor column should fail")
+	}
+}
+
+func TestMysqlCursorFields(t *testing.T) {
+	cases := []struct {
+		fields   []string
+		column   string
+		expected string
+	}{
+		{[]string{"name"}, "id", "name,id"},
+		{[]string{"name", "id"}, "id", "name,id"},
+		{[]string{"t.name"}, "t.id", "t.name,t.id"},
+		{[]string{"id", "name"}, "t.id", "id,name"},
+		{[]string{"t.*"}, "t.id", "t.*"},
+		{[]string{"*"}, "id", "*"},
+	}
+
+	for _, c := range cases {
+		if r := strings.Join(mysqlCursorFields(c.fields, c.column), ","); r != c.expected {
+			t.Errorf("fields %v column %s:%s", c.fields, c.column, r)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"sync/atomic"
+)
+
+var mysqlSavepointId uint64
+
+//mysqlTxKey ctx中db的事务,TransactionContext设置
+type mysqlTxKey struct {
+	db string
+}
+
+// Transaction run fn in transaction on write orm,fn返回error或panic时回滚,
+// fn中把tx传给Insert/Update/Delete/Select等方法即可参与事务
+func (p *Mysql) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return p.TransactionPlus(ctx, nil, fn)
+}
+
+// TransactionPlus run fn in transaction,db为nil时使用write orm,
+// db已经是事务时使用savepoint,fn失败只回滚到savepoint,
+// 分片的表需要WithShardKey(ctx, key, value)指定分片,事务不能跨分片
+func (p *Mysql) TransactionPlus(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return p.TransactionContext(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(tx)
+	})
+}
+
+// TransactionContext same as TransactionPlus,fn的ctx带有事务的span和tx,
+// fn中db传nil的方法(包括嵌套的Transaction)使用ctx中的tx,嵌套的事务使用savepoint,
+// ctx不能在事务结束后继续使用
+func (p *Mysql) TransactionContext(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) (err error) {
+	p, err = p.shardRoute(ctx, nil)
+	if err != nil {
+		return
+	}
+	span, ctx := p.ZipkinNewSpan(ctx, "transaction")
+	if span != nil {
+		defer span.Finish()
+	}
+
+	p.markWrite(ctx)
+
+	//ctx中已有事务时GetWriteOrm返回该事务
+	if db == nil {
+		db, err = p.GetWriteOrm(ctx)
+
+		if err != nil {
+			return
+		}
+	}
+
+	if mysqlIsTx(db) {
+		ctx = context.WithValue(ctx, mysqlTxKey{p.getDbName()}, db)
+
+		savepoint := fmt.Sprintf("tgo_sp_%d", atomic.AddUint64(&mysqlSavepointId, 1))
+
+		if span != nil {
+			span.SetTag("savepoint", savepoint)
+		}
+
+		if errSp := db.Exec("SAVEPOINT " + savepoint).Error; errSp != nil {
+			return p.processError(span, errSp, pconst.ERROR_MYSQL_TRANSACTION, "savepoint %s error:%s", savepoint, errSp.Error())
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				mysqlTxPanic(span, r)
+				db.Exec("ROLLBACK TO SAVEPOINT " + savepoint)
+				panic(r)
+			}
+		}()
+
+		if err = fn(ctx, db); err != nil {
+			mysqlTxFailed(span)
+			if errRb := db.Exec("ROLLBACK TO SAVEPOINT " + savepoint).Error; errRb != nil {
+				p.processError(span, errRb, pconst.ERROR_MYSQL_TRANSACTION, "rollback to savepoint %s error:%s", savepoint, errRb.Error())
+			}
+			return
+		}
+
+		if errRelease := db.Exec("RELEASE SAVEPOINT " + savepoint).Error; errRelease != nil {
+			err = p.processError(span, errRelease, pconst.ERROR_MYSQL_TRANSACTION, "release savepoint %s error:%s", savepoint, errRelease.Error())
+		}
+		return
+	}
+
+	tx := db.Begin()
+
+	if tx.Error != nil {
+		return p.processError(span, tx.Error, pconst.ERROR_MYSQL_TRANSACTION, "begin transaction error:%s", tx.Error.Error())
+	}
+
+	ctx = context.WithValue(ctx, mysqlTxKey{p.getDbName()}, tx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			mysqlTxPanic(span, r)
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(ctx, tx); err != nil {
+		mysqlTxFailed(span)
+		if errRb := tx.Rollback().Error; errRb != nil {
+			p.processError(span, errRb, pconst.ERROR_MYSQL_TRANSACTION, "rollback error:%s", errRb.Error())
+		}
+		return
+	}
+
+	if errCommit := tx.Commit().Error; errCommit != nil {
+		err = p.processError(span, errCommit, pconst.ERROR_MYSQL_TRANSACTION, "commit error:%s", errCommit.Error())
+	}
+	return
+}
+
+//mysqlTxGet transaction of db in ctx,nil if not exists
+func mysqlTxGet(ctx context.Context, dbName string) *gorm.DB {
+	tx, _ := ctx.Value(mysqlTxKey{dbName}).(*gorm.DB)
+	return tx
+}
+
+//mysqlIsTx whether db is in transaction
+func mysqlIsTx(db *gorm.DB) bool {
+	_, ok := db.CommonDB().(*sql.Tx)
+	return ok
+}
+
+func mysqlTxFailed(span opentracing.Span) {
+	if span != nil {
+		ext.Error.Set(span, true)
+		span.SetTag("rollback", true)
+	}
+}
+
+//mysqlTxPanic mark span failed when fn panic,panic继续向上抛
+func mysqlTxPanic(span opentracing.Span, r interface{}) {
+	mysqlTxFailed(span)
+	if span != nil {
+		span.LogKV("event", "panic", "message", fmt.Sprint(r))
+	}
+}
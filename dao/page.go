@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"reflect"
+)
+
+//pageTrim data为*[]T或*[]*T,超过size时截取到size,返回最后一个元素的指针以及是否有更多
+func pageTrim(data interface{}, size int) (last interface{}, hasMore bool) {
+	refValue := reflect.ValueOf(data)
+
+	if refValue.Kind() != reflect.Ptr || refValue.Elem().Kind() != reflect.Slice {
+		return
+	}
+
+	refSlice := refValue.Elem()
+
+	if size > 0 && refSlice.Len() > size {
+		hasMore = true
+		refSlice.Set(refSlice.Slice(0, size))
+	}
+
+	if refSlice.Len() == 0 {
+		return
+	}
+
+	item := refSlice.Index(refSlice.Len() - 1)
+
+	if item.Kind() == reflect.Ptr {
+		if item.IsNil() {
+			return
+		}
+		last = item.Interface()
+	} else {
+		last = item.Addr().Interface()
+	}
+	return
+}
@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/garyburd/redigo/redis"
 	"github.com/opentracing/opentracing-go"
@@ -12,6 +13,7 @@ import (
 	"github.com/tonyjt/tgo_v2/pconst"
 	"github.com/tonyjt/tgo_v2/terror"
 	"github.com/youtube/vitess/go/pools"
+	"net"
 	"reflect"
 	"strconv"
 	"strings"
@@ -126,12 +128,7 @@ type Redis struct {
 
 //ZipkinNewSpan new zipkin span for redis
 func (p *Redis) ZipkinNewSpan(ctx context.Context, name string) (opentracing.Span, context.Context) {
-
-	if config.FeatureZipkin() {
-		return opentracing.StartSpanFromContext(ctx, fmt.Sprintf("redis:%s:%s", name, p.Key))
-	} else {
-		return nil, ctx
-	}
+	return daoNewSpan(ctx, fmt.Sprintf("redis:%s:%s", name, p.Key), "redis", p.Key, name)
 }
 
 //GetConn get redis conn
@@ -162,14 +159,14 @@ func (p *Redis) GetConn(ctx context.Context) (conn pools.Resource, err error) {
 
 	if err != nil {
 		log.Errorf("redis get connection err:%s", err.Error())
-		err = terror.New(pconst.ERROR_REDIS_POOL_GET)
+		err = terror.New(pconst.ERROR_REDIS_POOL_GET).SetRetryable(true)
 		p.ZipkinTag(span, "err:pool", err)
 		return
 	}
 
 	if r == nil {
 		log.Error("redis pool resource is null")
-		err = terror.New(pconst.ERROR_REDIS_POOL_EMPTY)
+		err = terror.New(pconst.ERROR_REDIS_POOL_EMPTY).SetRetryable(true)
 		p.ZipkinTag(span, "err:pool", err)
 		return
 	}
@@ -194,7 +191,7 @@ func (p *Redis) GetConn(ctx context.Context) (conn pools.Resource, err error) {
 		if err != nil {
 			pool.Put(r)
 			log.Errorf("redis redail connection err:%s", err.Error())
-			err = terror.New(pconst.ERROR_REDIS_POOL_REDIAL)
+			err = terror.New(pconst.ERROR_REDIS_POOL_REDIAL).SetRetryable(true)
 
 			p.ZipkinTag(span, "err:dial", err)
 			return
@@ -256,7 +253,7 @@ func (p *Redis) Do(ctx context.Context, cmd string, args ...interface{}) (reply
 	if errDo != nil {
 		log.Errorf("run redis command %s failed:error:%s,args:%v", cmd, errDo.Error(), args)
 
-		err = terror.New(pconst.ERROR_REDIS_DO)
+		err = terror.New(pconst.ERROR_REDIS_DO).SetRetryable(redisRetryable(errDo))
 		p.ZipkinTag(span, "do"+cmd, err)
 	}
 	return
@@ -615,6 +612,12 @@ func (p *Redis) doDel(ctx context.Context, cmd string, data ...interface{}) (err
 	return
 }
 
+//redisRetryable 只有连接失败可以重试,命令发出后的错误(如读超时)重试可能让INCR,LPUSH等执行两次
+func redisRetryable(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
 //ZipkinTag
 func (p *Redis) ZipkinTag(span opentracing.Span, tag string, err error) {
 	if span != nil {
@@ -2,6 +2,10 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/tonyjt/tgo_v2/tracing/tracetest"
+	"net"
 	"testing"
 )
 
@@ -30,6 +34,9 @@ func TestRedis_Set(t *testing.T) {
 }
 
 func TestRedis_Get(t *testing.T) {
+	r := tracetest.Install()
+	defer r.Uninstall()
+
 	redis := NewRedisTest()
 
 	var data string
@@ -40,6 +47,8 @@ func TestRedis_Get(t *testing.T) {
 	} else if !exists {
 		t.Error("result false")
 	}
+
+	r.AssertError(t, "redis:GET:test", err != nil)
 }
 
 func TestRedis_SetEx(t *testing.T) {
@@ -290,3 +299,19 @@ func (c *TestRedis) MSet(value map[string]ModelRedisHello) error {
 	}
 	return c.Redis.MSet(context.Background(), datas)
 }
+
+func TestRedisRetryable(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}
+
+	if !redisRetryable(dial) || !redisRetryable(fmt.Errorf("wrapped:%w", dial)) {
+		t.Error("dial error should be retryable")
+	}
+	//命令可能已经执行
+	if redisRetryable(read) {
+		t.Error("read error should not be retryable")
+	}
+	if redisRetryable(errors.New("ERR wrong type")) {
+		t.Error("redis error should not be retryable")
+	}
+}
@@ -0,0 +1,21 @@
+package dao
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/metrics"
+)
+
+//daoNewSpan new span of dao operation,metrics开启时记录延迟和错误
+func daoNewSpan(ctx context.Context, spanName string, backend string, instance string, operation string) (opentracing.Span, context.Context) {
+	var span opentracing.Span
+
+	if config.FeatureZipkin() {
+		span, ctx = opentracing.StartSpanFromContext(ctx, spanName)
+	}
+	if config.FeatureMetrics() {
+		span = metrics.Span(span, backend, instance, operation)
+	}
+	return span, ctx
+}
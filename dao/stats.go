@@ -0,0 +1,238 @@
+package dao
+
+import (
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/youtube/vitess/go/pools"
+	"gopkg.in/mgo.v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//RedisPoolStats stats of redis pool
+type RedisPoolStats struct {
+	Capacity    int64
+	Available   int64
+	MaxCap      int64
+	WaitCount   int64
+	WaitTime    time.Duration
+	IdleTimeout time.Duration
+	IdleClosed  int64
+}
+
+//MysqlStats stats of mysql sql.DB
+type MysqlStats struct {
+	Db    string
+	Role  string //write,read
+	Index int    //read的序号
+
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+	WaitCount          int64
+	WaitDuration       time.Duration
+	MaxIdleClosed      int64
+	MaxLifetimeClosed  int64
+}
+
+//MongoStats stats of mgo
+type MongoStats struct {
+	LiveServers []string
+	Sockets     *MongoSocketStats `json:",omitempty"` //mgo只在feature配置Metrics开启时统计socket(mgo.SetStats),未开启时为nil
+}
+
+//MongoSocketStats socket stats of mgo
+type MongoSocketStats struct {
+	SocketsAlive int
+	SocketsInUse int
+	SocketRefs   int
+	MasterConns  int
+	SlaveConns   int
+}
+
+//Stats pool stats of all dao
+type Stats struct {
+	Redis map[string]*RedisPoolStats //unpersist,persist
+	Mysql []*MysqlStats
+	Mongo *MongoStats
+	Grpc  map[string]string //service:connectivity state
+}
+
+func init() {
+	if config.FeatureMetrics() {
+		prometheus.MustRegister(newStatsCollector())
+	}
+}
+
+//StatsGet get pool stats
+func StatsGet() *Stats {
+	stats := &Stats{
+		Redis: make(map[string]*RedisPoolStats),
+		Grpc:  make(map[string]string),
+	}
+
+	if unpersist != nil {
+		stats.Redis["unpersist"] = redisPoolStatsGet(unpersist)
+	}
+	if persist != nil {
+		stats.Redis["persist"] = redisPoolStatsGet(persist)
+	}
+
+	for db, d := range dbMysqlWrite {
+		stats.Mysql = append(stats.Mysql, mysqlStatsGet(db, "write", 0, d.DB().Stats()))
+	}
+	for db, reads := range dbMysqlReads {
+		for i, r := range reads {
+			if d, _ := r.get(); d != nil {
+				stats.Mysql = append(stats.Mysql, mysqlStatsGet(db, "read", i, d.DB().Stats()))
+			}
+		}
+	}
+
+	if sessionMongo != nil {
+		stats.Mongo = &MongoStats{LiveServers: sessionMongo.LiveServers()}
+
+		if config.FeatureMetrics() {
+			s := mgo.GetStats()
+			stats.Mongo.Sockets = &MongoSocketStats{
+				SocketsAlive: s.SocketsAlive,
+				SocketsInUse: s.SocketsInUse,
+				SocketRefs:   s.SocketRefs,
+				MasterConns:  s.MasterConns,
+				SlaveConns:   s.SlaveConns,
+			}
+		}
+	}
+
+	grpcConnMux.RLock()
+	for service, conn := range grpcConnMap {
+		if conn != nil {
+			stats.Grpc[service] = conn.GetState().String()
+		}
+	}
+	grpcConnMux.RUnlock()
+
+	return stats
+}
+
+//StatsHandler gin handler of pool stats
+func StatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, StatsGet())
+	}
+}
+
+func mysqlStatsGet(db string, role string, index int, s sql.DBStats) *MysqlStats {
+	return &MysqlStats{
+		Db:                 db,
+		Role:               role,
+		Index:              index,
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+func redisPoolStatsGet(pool *pools.ResourcePool) *RedisPoolStats {
+	return &RedisPoolStats{
+		Capacity:    pool.Capacity(),
+		Available:   pool.Available(),
+		MaxCap:      pool.MaxCap(),
+		WaitCount:   pool.WaitCount(),
+		WaitTime:    pool.WaitTime(),
+		IdleTimeout: pool.IdleTimeout(),
+		IdleClosed:  pool.IdleClosed(),
+	}
+}
+
+//statsCollector prometheus collector of pool stats
+type statsCollector struct {
+	redisCapacity  *prometheus.Desc
+	redisAvailable *prometheus.Desc
+	redisWaitCount *prometheus.Desc
+	redisWaitTime  *prometheus.Desc
+
+	mysqlMaxOpen   *prometheus.Desc
+	mysqlOpen      *prometheus.Desc
+	mysqlInUse     *prometheus.Desc
+	mysqlIdle      *prometheus.Desc
+	mysqlWaitCount *prometheus.Desc
+	mysqlWaitTime  *prometheus.Desc
+
+	mongoSocketsAlive *prometheus.Desc
+	mongoSocketsInUse *prometheus.Desc
+
+	grpcState *prometheus.Desc
+}
+
+func newStatsCollector() *statsCollector {
+	redisLabels := []string{"pool"}
+	mysqlLabels := []string{"db", "role", "index"}
+
+	return &statsCollector{
+		redisCapacity:  prometheus.NewDesc("tgo_redis_pool_capacity", "Capacity of redis pool.", redisLabels, nil),
+		redisAvailable: prometheus.NewDesc("tgo_redis_pool_available", "Available resources of redis pool.", redisLabels, nil),
+		redisWaitCount: prometheus.NewDesc("tgo_redis_pool_wait_total", "Waits of redis pool.", redisLabels, nil),
+		redisWaitTime:  prometheus.NewDesc("tgo_redis_pool_wait_seconds_total", "Wait time of redis pool.", redisLabels, nil),
+
+		mysqlMaxOpen:   prometheus.NewDesc("tgo_mysql_max_open_connections", "Max open connections of mysql.", mysqlLabels, nil),
+		mysqlOpen:      prometheus.NewDesc("tgo_mysql_open_connections", "Open connections of mysql.", mysqlLabels, nil),
+		mysqlInUse:     prometheus.NewDesc("tgo_mysql_in_use_connections", "In use connections of mysql.", mysqlLabels, nil),
+		mysqlIdle:      prometheus.NewDesc("tgo_mysql_idle_connections", "Idle connections of mysql.", mysqlLabels, nil),
+		mysqlWaitCount: prometheus.NewDesc("tgo_mysql_wait_total", "Waits for mysql connection.", mysqlLabels, nil),
+		mysqlWaitTime:  prometheus.NewDesc("tgo_mysql_wait_seconds_total", "Wait time for mysql connection.", mysqlLabels, nil),
+
+		mongoSocketsAlive: prometheus.NewDesc("tgo_mongo_sockets_alive", "Alive sockets of mgo.", nil, nil),
+		mongoSocketsInUse: prometheus.NewDesc("tgo_mongo_sockets_in_use", "In use sockets of mgo.", nil, nil),
+
+		grpcState: prometheus.NewDesc("tgo_grpc_conn_state", "State of grpc connection,value is 1.", []string{"service", "state"}, nil),
+	}
+}
+
+func (p *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{p.redisCapacity, p.redisAvailable, p.redisWaitCount, p.redisWaitTime,
+		p.mysqlMaxOpen, p.mysqlOpen, p.mysqlInUse, p.mysqlIdle, p.mysqlWaitCount, p.mysqlWaitTime,
+		p.mongoSocketsAlive, p.mongoSocketsInUse, p.grpcState} {
+		ch <- desc
+	}
+}
+
+func (p *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := StatsGet()
+
+	for name, s := range stats.Redis {
+		ch <- prometheus.MustNewConstMetric(p.redisCapacity, prometheus.GaugeValue, float64(s.Capacity), name)
+		ch <- prometheus.MustNewConstMetric(p.redisAvailable, prometheus.GaugeValue, float64(s.Available), name)
+		ch <- prometheus.MustNewConstMetric(p.redisWaitCount, prometheus.CounterValue, float64(s.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(p.redisWaitTime, prometheus.CounterValue, s.WaitTime.Seconds(), name)
+	}
+
+	for _, s := range stats.Mysql {
+		labels := []string{s.Db, s.Role, strconv.Itoa(s.Index)}
+
+		ch <- prometheus.MustNewConstMetric(p.mysqlMaxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(p.mysqlOpen, prometheus.GaugeValue, float64(s.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(p.mysqlInUse, prometheus.GaugeValue, float64(s.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(p.mysqlIdle, prometheus.GaugeValue, float64(s.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(p.mysqlWaitCount, prometheus.CounterValue, float64(s.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(p.mysqlWaitTime, prometheus.CounterValue, s.WaitDuration.Seconds(), labels...)
+	}
+
+	if stats.Mongo != nil && stats.Mongo.Sockets != nil {
+		ch <- prometheus.MustNewConstMetric(p.mongoSocketsAlive, prometheus.GaugeValue, float64(stats.Mongo.Sockets.SocketsAlive))
+		ch <- prometheus.MustNewConstMetric(p.mongoSocketsInUse, prometheus.GaugeValue, float64(stats.Mongo.Sockets.SocketsInUse))
+	}
+
+	for service, state := range stats.Grpc {
+		ch <- prometheus.MustNewConstMetric(p.grpcState, prometheus.GaugeValue, 1, service, state)
+	}
+}
@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/tonyjt/tgo_v2/config"
+	"testing"
+	"time"
+)
+
+func TestStatsGet_Mysql(t *testing.T) {
+	m := testInit()
+
+	var s []m1
+	if err := m.Find(context.Background(), nil, m.Q().Eq("name", "test1").Limit(1), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	var write *MysqlStats
+	for _, stat := range StatsGet().Mysql {
+		if stat.Db == m.DbName && stat.Role == "write" {
+			write = stat
+		}
+	}
+
+	if write == nil {
+		t.Fatalf("stats of %s write not found", m.DbName)
+	}
+	if max := config.MysqlGet(m.DbName).Conn.Pool.Max; write.MaxOpenConnections != max {
+		t.Errorf("max open connections should be %d:%d", max, write.MaxOpenConnections)
+	}
+	if write.InUse != 0 {
+		t.Errorf("connections should be released:%d", write.InUse)
+	}
+}
+
+func TestStatsGet_Redis(t *testing.T) {
+	redis := NewRedisTest()
+
+	if err := redis.Set(context.Background(), "stats", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := StatsGet()
+
+	for _, name := range []string{"unpersist", "persist"} {
+		s, ok := stats.Redis[name]
+		if !ok {
+			t.Errorf("stats of redis %s not found", name)
+			continue
+		}
+		if s.Capacity <= 0 || s.Available > s.Capacity {
+			t.Errorf("stats of redis %s is invalid:%+v", name, s)
+		}
+	}
+}
+
+func TestStatsGet_MongoSockets(t *testing.T) {
+	stats := StatsGet()
+
+	if stats.Mongo == nil {
+		t.Skip("mongo is not enabled")
+	}
+	if (stats.Mongo.Sockets != nil) != config.FeatureMetrics() {
+		t.Errorf("sockets stats should only exist when metrics enabled:%+v", stats.Mongo.Sockets)
+	}
+}
+
+func TestMysqlStatsGet(t *testing.T) {
+	s := mysqlStatsGet("tgo1", "read", 1, sql.DBStats{MaxOpenConnections: 16, OpenConnections: 3, InUse: 1, Idle: 2,
+		WaitCount: 5, WaitDuration: time.Second})
+
+	if s.Db != "tgo1" || s.Role != "read" || s.Index != 1 {
+		t.Errorf("labels error:%+v", s)
+	}
+	if s.MaxOpenConnections != 16 || s.OpenConnections != 3 || s.InUse != 1 || s.Idle != 2 ||
+		s.WaitCount != 5 || s.WaitDuration != time.Second {
+		t.Errorf("stats error:%+v", s)
+	}
+}
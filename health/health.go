@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+//Checker check component,return nil if healthy
+type Checker func(ctx context.Context) error
+
+//Status status of component
+type Status struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
+	Duration  int64  `json:"duration_ms"`
+	CheckedAt int64  `json:"checked_at"`
+}
+
+type checker struct {
+	check    Checker
+	critical bool
+}
+
+var (
+	checkers = make(map[string]*checker)
+	mux      sync.RWMutex
+
+	cache    []*Status
+	cacheAt  time.Time
+	cacheMux sync.Mutex
+)
+
+//Register register checker,replace if name exists,
+//critical的组件down时readiness返回503,其他的只在components中显示,如mysql的reader
+func Register(name string, check Checker, critical bool) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	checkers[name] = &checker{check: check, critical: critical}
+}
+
+//Unregister remove checker
+func Unregister(name string) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	delete(checkers, name)
+}
+
+//Check run all checkers concurrently,结果缓存CacheTtl,critical的组件都up时ok
+func Check(ctx context.Context) (ok bool, statuses []*Status) {
+	conf := config.HealthGet()
+
+	cacheMux.Lock()
+	defer cacheMux.Unlock()
+
+	statuses = cache
+	if statuses == nil || time.Since(cacheAt) >= time.Duration(conf.CacheTtl)*time.Millisecond {
+		statuses = check(ctx, time.Duration(conf.Timeout)*time.Millisecond)
+
+		//请求被取消时所有组件都会是down,不缓存,避免影响其他探针
+		if ctx.Err() == nil {
+			cache = statuses
+			cacheAt = time.Now()
+		}
+	}
+
+	ok = true
+	for _, s := range statuses {
+		if s.Critical && s.Status != StatusUp {
+			ok = false
+		}
+	}
+	return ok, statuses
+}
+
+func check(ctx context.Context, timeout time.Duration) []*Status {
+	mux.RLock()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]*Status, len(names))
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, c *checker) {
+			defer wg.Done()
+			statuses[i] = checkOne(ctx, name, c, timeout)
+		}(i, name, checkers[name])
+	}
+	mux.RUnlock()
+
+	wg.Wait()
+
+	return statuses
+}
+
+func checkOne(ctx context.Context, name string, c *checker, timeout time.Duration) *Status {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- errors.New("checker panic")
+			}
+		}()
+		result <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	status := &Status{
+		Name:      name,
+		Status:    StatusUp,
+		Critical:  c.critical,
+		Duration:  int64(time.Since(start) / time.Millisecond),
+		CheckedAt: start.Unix(),
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
+
+//HandlerReadiness gin handler for readiness probe,有critical的组件down时返回503
+func HandlerReadiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, statuses := Check(c.Request.Context())
+
+		code, status := http.StatusOK, StatusUp
+		if !ok {
+			code, status = http.StatusServiceUnavailable, StatusDown
+		}
+		c.JSON(code, gin.H{"status": status, "components": statuses})
+	}
+}
+
+//HandlerLiveness gin handler for liveness probe,进程能响应即可,不检查后端
+func HandlerLiveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+	}
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	Register("up", func(ctx context.Context) error { return nil }, true)
+	Register("down", func(ctx context.Context) error { return errors.New("down") }, true)
+	Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Minute)
+		return nil
+	}, true)
+	defer func() {
+		Unregister("up")
+		Unregister("down")
+		Unregister("slow")
+		cache = nil
+	}()
+
+	ok, statuses := Check(context.Background())
+
+	if ok || len(statuses) != 3 {
+		t.Fatalf("ok:%t,statuses:%d", ok, len(statuses))
+	}
+	//按name排序
+	if statuses[0].Name != "down" || statuses[0].Status != StatusDown || statuses[0].Error != "down" {
+		t.Errorf("down status:%+v", statuses[0])
+	}
+	if statuses[1].Name != "slow" || statuses[1].Status != StatusDown {
+		t.Errorf("slow status:%+v", statuses[1])
+	}
+	if statuses[2].Name != "up" || statuses[2].Status != StatusUp {
+		t.Errorf("up status:%+v", statuses[2])
+	}
+
+	Unregister("down")
+	if _, cached := Check(context.Background()); len(cached) != 3 {
+		t.Errorf("result should be cached")
+	}
+}
+
+func TestCheck_Canceled(t *testing.T) {
+	Register("up", func(ctx context.Context) error { return ctx.Err() }, true)
+	defer func() {
+		Unregister("up")
+		cache = nil
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if ok, _ := Check(ctx); ok {
+		t.Errorf("checker should be down when ctx is canceled")
+	}
+	if ok, statuses := Check(context.Background()); !ok || len(statuses) != 1 {
+		t.Errorf("result of canceled ctx should not be cached,ok:%t", ok)
+	}
+}
+
+func TestCheck_Critical(t *testing.T) {
+	Register("writer", func(ctx context.Context) error { return nil }, true)
+	Register("reader", func(ctx context.Context) error { return errors.New("down") }, false)
+	defer func() {
+		Unregister("writer")
+		Unregister("reader")
+		cache = nil
+	}()
+
+	ok, statuses := Check(context.Background())
+
+	if !ok || len(statuses) != 2 {
+		t.Fatalf("non-critical down should be ok,ok:%t,statuses:%d", ok, len(statuses))
+	}
+	if statuses[0].Name != "reader" || statuses[0].Status != StatusDown || statuses[0].Critical {
+		t.Errorf("reader status:%+v", statuses[0])
+	}
+
+	Register("writer", func(ctx context.Context) error { return errors.New("down") }, true)
+	cache = nil
+
+	if ok, _ := Check(context.Background()); ok {
+		t.Error("critical down should not be ok")
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"github.com/sirupsen/logrus"
 	"github.com/tonyjt/tgo_v2/config"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -19,8 +20,13 @@ const (
 	LevelDebug
 )
 
+//ContextFields fields of ctx added to log,如trace id,user id
+type ContextFields func(ctx context.Context) map[string]interface{}
+
 var (
 	logger *logrus.Logger
+
+	contextFields []ContextFields
 )
 
 func init() {
@@ -77,6 +83,92 @@ func Logf(level Level, format string, msg ...interface{}) {
 	}
 }
 
+//SetLevel change log level at runtime
+func SetLevel(level Level) {
+	logger.SetLevel(logrus.Level(level))
+}
+
+//GetLevel current log level
+func GetLevel() Level {
+	return Level(logger.GetLevel())
+}
+
+//ParseLevel parse level name,如error,info
+func ParseLevel(name string) (Level, error) {
+	level, err := logrus.ParseLevel(name)
+
+	return Level(level), err
+}
+
+//String level name
+func (l Level) String() string {
+	return logrus.Level(l).String()
+}
+
+//RegisterContextFields register fields getter used by LogCtx and LogfCtx,需要在init中注册
+func RegisterContextFields(fields ContextFields) {
+	contextFields = append(contextFields, fields)
+}
+
+//entry logrus entry with fields of ctx
+func entry(ctx context.Context) *logrus.Entry {
+	fields := logrus.Fields{}
+
+	if ctx != nil {
+		for _, f := range contextFields {
+			for k, v := range f(ctx) {
+				fields[k] = v
+			}
+		}
+	}
+	return logger.WithFields(fields)
+}
+
+//LogCtx log with fields of ctx
+func LogCtx(ctx context.Context, level Level, msg ...interface{}) {
+	e := entry(ctx)
+
+	switch level {
+	case LevelDebug:
+		e.Debug(msg...)
+	case LevelInfo:
+		e.Info(msg...)
+	case LevelWarn:
+		e.Warn(msg...)
+	case LevelError:
+		e.Error(msg...)
+	case LevelFatal:
+		e.Fatal(msg...)
+	case LevelPanic:
+		e.Panic(msg...)
+	}
+}
+
+//LogfCtx logf with fields of ctx
+func LogfCtx(ctx context.Context, level Level, format string, msg ...interface{}) {
+	e := entry(ctx)
+
+	switch level {
+	case LevelDebug:
+		e.Debugf(format, msg...)
+	case LevelInfo:
+		e.Infof(format, msg...)
+	case LevelWarn:
+		e.Warnf(format, msg...)
+	case LevelError:
+		e.Errorf(format, msg...)
+	case LevelFatal:
+		e.Fatalf(format, msg...)
+	case LevelPanic:
+		e.Panicf(format, msg...)
+	}
+}
+
+//ErrorfCtx errorf with fields of ctx
+func ErrorfCtx(ctx context.Context, format string, msg ...interface{}) {
+	LogfCtx(ctx, LevelError, format, msg...)
+}
+
 //Errorf errorf
 func Errorf(format string, msg ...interface{}) {
 	Logf(LevelError, format, msg...)
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+const namespace = "tgo"
+
+var (
+	daoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "dao",
+		Name:      "duration_seconds",
+		Help:      "Latency of dao operations.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend", "instance", "operation"})
+
+	daoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dao",
+		Name:      "errors_total",
+		Help:      "Errors of dao operations.",
+	}, []string{"backend", "instance", "operation"})
+
+	codes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "terror_total",
+		Help:      "TError codes by source,source为dao backend或response.",
+	}, []string{"source", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(daoDuration, daoErrors, codes)
+}
+
+//Observe record latency and error of dao operation
+func Observe(backend string, instance string, operation string, start time.Time, isError bool) {
+	daoDuration.WithLabelValues(backend, instance, operation).Observe(time.Since(start).Seconds())
+
+	if isError {
+		daoErrors.WithLabelValues(backend, instance, operation).Inc()
+	}
+}
+
+//CodeInc increase counter of TError code
+func CodeInc(source string, code int) {
+	codes.WithLabelValues(source, strconv.Itoa(code)).Inc()
+}
+
+//Handler gin handler for /metrics,router.GET("/metrics", metrics.Handler())
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/tonyjt/tgo_v2/terror"
+	"time"
+)
+
+//span record metrics when finished,error由ext.Error标记,code由*terror.TError类型的tag获取
+type span struct {
+	opentracing.Span
+
+	backend   string
+	instance  string
+	operation string
+	start     time.Time
+	isError   bool
+}
+
+//Span wrap span to record metrics of dao operation,span为nil时使用noop span
+func Span(s opentracing.Span, backend string, instance string, operation string) opentracing.Span {
+	if s == nil {
+		s = opentracing.NoopTracer{}.StartSpan(operation)
+	}
+	return &span{
+		Span:      s,
+		backend:   backend,
+		instance:  instance,
+		operation: operation,
+		start:     time.Now(),
+	}
+}
+
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	if key == string(ext.Error) {
+		if b, ok := value.(bool); ok && b {
+			s.isError = true
+		}
+	}
+	if te, ok := value.(*terror.TError); ok {
+		CodeInc(s.backend, te.Code)
+	}
+	s.Span.SetTag(key, value)
+
+	return s
+}
+
+func (s *span) Finish() {
+	Observe(s.backend, s.instance, s.operation, s.start, s.isError)
+
+	s.Span.Finish()
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	Observe(s.backend, s.instance, s.operation, s.start, s.isError)
+
+	s.Span.FinishWithOptions(opts)
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tonyjt/tgo_v2/terror"
+	"testing"
+)
+
+func TestSpan(t *testing.T) {
+	span := Span(nil, "mysql", "tgo1", "insert")
+	span.Finish()
+
+	ext.Error.Set(span, true)
+	span.SetTag("err", terror.New(10301))
+	span.Finish()
+
+	if count := testutil.ToFloat64(daoErrors.WithLabelValues("mysql", "tgo1", "insert")); count != 1 {
+		t.Errorf("error count:%f", count)
+	}
+	if count := testutil.ToFloat64(codes.WithLabelValues("mysql", "10301")); count != 1 {
+		t.Errorf("code count:%f", count)
+	}
+}
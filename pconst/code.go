@@ -3,6 +3,9 @@ package pconst
 const (
 	ERROR_OK              = 1001
 	ERROR_MYSQL_NOT_FOUND = 100
+
+	ERROR_REQUEST_BIND     = 101
+	ERROR_REQUEST_VALIDATE = 102
 )
 const (
 	ERROR_SYSTEM = 10000
@@ -55,6 +58,12 @@ const (
 	ERROR_MYSQL_COUNT = 10108
 
 	ERROR_MYSQL_INVOKE = 10109
+
+	ERROR_MYSQL_TRANSACTION = 10110
+
+	ERROR_MYSQL_SHARD = 10111
+
+	ERROR_MYSQL_QUERY = 10112
 )
 
 const (
@@ -126,6 +135,8 @@ const (
 	ERROR_GRPC_DAIL = 10402
 
 	ERROR_GRPC_INVOKE = 10403
+
+	ERROR_GRPC_STREAM = 10404
 )
 
 const (
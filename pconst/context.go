@@ -0,0 +1,7 @@
+package pconst
+
+//gin context中的key
+const (
+	CONTEXT_KEY_RESULT = "result" //bool,是否成功
+	CONTEXT_KEY_CODE   = "code"   //int,业务code
+)
@@ -0,0 +1,165 @@
+package tgo_v2
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+//requestFieldError field error of validator(v9,v10)
+type requestFieldError interface {
+	Field() string
+	StructNamespace() string
+	Tag() string
+	Param() string
+}
+
+//RequestBind bind path,query and body(form/json by content type) params into obj,then validate by binding tag,
+//return TError with config Resp.Validate.Code and field messages in Details
+func RequestBind(c *gin.Context, obj interface{}) error {
+
+	var binds []func() error
+
+	if len(c.Params) > 0 {
+		binds = append(binds, func() error { return c.ShouldBindUri(obj) })
+	}
+	if c.Request.URL.RawQuery != "" {
+		binds = append(binds, func() error { return c.ShouldBindQuery(obj) })
+	}
+	if c.Request.Method != http.MethodGet && c.Request.ContentLength != 0 {
+		binds = append(binds, func() error { return c.ShouldBind(obj) })
+	}
+
+	for _, bind := range binds {
+		//各来源绑定时的校验错误忽略,全部绑定后统一校验
+		if err := bind(); err != nil && requestFieldErrors(err) == nil {
+			log.Errorf("request bind error:%s", err.Error())
+			return terror.New(pconst.ERROR_REQUEST_BIND)
+		}
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return RequestValidateError(binding.Validator.ValidateStruct(obj), obj)
+}
+
+//RequestValidateError convert validation error of obj to TError,field messages are localized by config.CodeGetMsg,
+//details的key为json/form tag的名字,obj为nil时使用struct字段名
+func RequestValidateError(err error, obj interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	conf := config.RespGet().Validate
+
+	te := terror.New(conf.Code)
+
+	fieldErrors := requestFieldErrors(err)
+
+	if fieldErrors == nil {
+		te.MsgCustom = err.Error()
+		return te
+	}
+
+	for _, fe := range fieldErrors {
+		var msg string
+
+		field := requestFieldName(obj, fe)
+
+		if code, ok := conf.Tags[fe.Tag()]; ok {
+			msg = strings.NewReplacer("{field}", field, "{param}", fe.Param()).Replace(config.CodeGetMsg(code))
+		} else if fe.Param() != "" {
+			msg = fmt.Sprintf("%s:%s=%s", field, fe.Tag(), fe.Param())
+		} else {
+			msg = fmt.Sprintf("%s:%s", field, fe.Tag())
+		}
+		te.SetDetail(field, msg)
+	}
+	return te
+}
+
+//requestFieldErrors get field errors from validator.ValidationErrors,nil if err is not
+func requestFieldErrors(err error) (fieldErrors []requestFieldError) {
+	refValue := reflect.ValueOf(err)
+
+	if refValue.Kind() != reflect.Slice {
+		return
+	}
+
+	for i := 0; i < refValue.Len(); i++ {
+		fe, ok := refValue.Index(i).Interface().(requestFieldError)
+		if !ok {
+			return nil
+		}
+		fieldErrors = append(fieldErrors, fe)
+	}
+	return
+}
+
+//requestFieldName name of field sent by client,按StructNamespace逐级取json/form/uri tag,如Req.Addr.CityId为addr.city_id
+func requestFieldName(obj interface{}, fe requestFieldError) string {
+	if obj == nil {
+		return fe.Field()
+	}
+
+	typ := reflect.TypeOf(obj)
+
+	parts := strings.Split(fe.StructNamespace(), ".")
+
+	var names []string
+
+	for _, part := range parts[1:] {
+		var index string
+
+		if i := strings.Index(part, "["); i >= 0 {
+			part, index = part[:i], part[i:]
+		}
+
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+
+		if typ.Kind() != reflect.Struct {
+			return fe.Field()
+		}
+
+		field, ok := typ.FieldByName(part)
+
+		if !ok {
+			return fe.Field()
+		}
+		typ = field.Type
+
+		name, tagged := requestTagName(field)
+
+		//没有tag的嵌入struct,字段直接在上一级
+		if field.Anonymous && !tagged {
+			continue
+		}
+		names = append(names, name+index)
+	}
+
+	if len(names) == 0 {
+		return fe.Field()
+	}
+	return strings.Join(names, ".")
+}
+
+//requestTagName json,form,uri tag中的名字,都没有返回字段名
+func requestTagName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"json", "form", "uri"} {
+		if name := strings.Split(field.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+			return name, true
+		}
+	}
+	return field.Name, false
+}
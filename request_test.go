@@ -0,0 +1,90 @@
+package tgo_v2
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testRequestAddr struct {
+	CityId int `json:"city_id" binding:"required"`
+}
+
+type testRequestBase struct {
+	AppId string `form:"app_id" json:"app_id" binding:"required"`
+}
+
+type testRequest struct {
+	testRequestBase
+	UserId int64           `form:"user_id" json:"user_id" binding:"required"`
+	Name   string          `form:"name" json:"name" binding:"required"`
+	Addr   testRequestAddr `json:"addr"`
+}
+
+func testRequestBind(method string, target string, contentType string, body string) (*testRequest, error) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+
+	req := &testRequest{}
+
+	return req, RequestBind(c, req)
+}
+
+func TestRequestBind(t *testing.T) {
+	req, err := testRequestBind(http.MethodPost, "/test?app_id=a", "application/json", `{"user_id":1,"name":"n","addr":{"city_id":2}}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.AppId != "a" || req.UserId != 1 || req.Name != "n" || req.Addr.CityId != 2 {
+		t.Errorf("request:%+v", req)
+	}
+
+	req, err = testRequestBind(http.MethodGet, "/test?app_id=a&user_id=3&name=n", "", "")
+
+	if err == nil || req.UserId != 3 {
+		t.Errorf("query should be bound and addr.city_id required:%+v,%v", req, err)
+	}
+}
+
+func TestRequestBind_Error(t *testing.T) {
+	_, err := testRequestBind(http.MethodPost, "/test", "application/json", `{"user_id":`)
+
+	if te, ok := err.(*terror.TError); !ok || te.Code != pconst.ERROR_REQUEST_BIND {
+		t.Errorf("invalid json should be bind error:%v", err)
+	}
+}
+
+func TestRequestValidateError(t *testing.T) {
+	_, err := testRequestBind(http.MethodPost, "/test", "application/json", `{"name":"n"}`)
+
+	te, ok := err.(*terror.TError)
+
+	if !ok || te.Code != config.RespGet().Validate.Code {
+		t.Fatalf("should be validate error:%v", err)
+	}
+
+	for _, key := range []string{"user_id", "app_id", "addr.city_id"} {
+		if _, ok := te.GetDetail(key); !ok {
+			t.Errorf("detail %s not found:%v", key, te.Details)
+		}
+	}
+	if _, ok := te.GetDetail("UserId"); ok {
+		t.Errorf("detail key should be json name:%v", te.Details)
+	}
+
+	if RequestValidateError(nil, nil) != nil {
+		t.Error("nil error should be nil")
+	}
+}
@@ -3,15 +3,29 @@ package tgo_v2
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/tonyjt/tgo_v2/config"
 	"github.com/tonyjt/tgo_v2/log"
+	"github.com/tonyjt/tgo_v2/metrics"
 	"github.com/tonyjt/tgo_v2/pconst"
 	"github.com/tonyjt/tgo_v2/terror"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 )
 
+const (
+	responseJsonpCallbackMaxLen = 128
+
+	metricsSourceResponse = "response"
+)
+
+var (
+	responseJsonpCallbackRegexp = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+)
+
 //ResponseReturnJSONNoP return json response without jsonp
 func ResponseReturnJSONNoP(c *gin.Context, err error, model interface{}) {
 
@@ -25,8 +39,119 @@ func ResponseJson(c *gin.Context, err error, model interface{}) {
 
 //ResponseJSONWithCallbackFlag  json response base func
 func ResponseJSONWithCallbackFlag(c *gin.Context, err error, model interface{}, callbackFlag bool) {
+	responseJSON(c, err, model, callbackFlag, config.RespGet().Status.Enable)
+}
+
+//ResponseRest json response,http status is mapped from code by config Resp.Status even if it is not enabled
+func ResponseRest(c *gin.Context, err error, model interface{}) {
+	responseJSON(c, err, model, false, true)
+}
+
+//responseJSON json response,statusFlag decides whether http status is mapped from code
+func responseJSON(c *gin.Context, err error, model interface{}, callbackFlag bool, statusFlag bool) {
 	var rj interface{}
 
+	te := responseTError(c, err)
+
+	rj = responseBody(c, te, model)
+
+	var callback string
+	if callbackFlag && !config.RespGet().Jsonp.Disable {
+		callback = c.Query("callback")
+
+		if callback != "" && !responseJsonpAllowed(c, callback) {
+			callback = ""
+		}
+	}
+
+	header := c.Writer.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if strings.Trim(callback, " ") == "" {
+		status := http.StatusOK
+		if statusFlag {
+			status = responseStatus(te)
+		}
+		c.Status(status)
+
+		if val := header["Content-Type"]; len(val) == 0 {
+			header["Content-Type"] = []string{"application/json; charset=utf-8"}
+		}
+
+		encoder := json.NewEncoder(c.Writer)
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(rj)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		b, err := responseJSONMarshal(rj)
+		if err != nil {
+			log.Errorf("jsonp marshal error:%s", err.Error())
+		} else {
+			header.Set("Content-Type", "application/javascript; charset=utf-8")
+			c.Status(http.StatusOK)
+			//注释前缀防止rosetta flash等攻击
+			c.Writer.WriteString(fmt.Sprintf("/**/%s(%s);", callback, bytes.TrimRight(b, "\n")))
+		}
+	}
+}
+
+//responseJsonpAllowed check callback name and referer
+func responseJsonpAllowed(c *gin.Context, callback string) bool {
+	if len(callback) > responseJsonpCallbackMaxLen || !responseJsonpCallbackRegexp.MatchString(callback) {
+		log.Errorf("jsonp callback invalid:%s", callback)
+		return false
+	}
+
+	origins := config.RespGet().Jsonp.Origins
+
+	if len(origins) == 0 {
+		return true
+	}
+
+	referer, err := url.Parse(c.Request.Referer())
+	if err != nil || referer.Host == "" {
+		return false
+	}
+
+	host := referer.Hostname()
+	origin := fmt.Sprintf("%s://%s", referer.Scheme, referer.Host)
+
+	for _, o := range origins {
+		//没有scheme的只允许https
+		scheme := "https"
+		if i := strings.Index(o, "://"); i >= 0 {
+			scheme, o = o[:i], o[i+3:]
+		}
+		if referer.Scheme != scheme {
+			continue
+		}
+
+		if strings.HasPrefix(o, "*.") {
+			if strings.HasSuffix(host, o[1:]) {
+				return true
+			}
+		} else if o == referer.Host {
+			return true
+		}
+	}
+
+	log.Errorf("jsonp referer not allowed:%s", origin)
+	return false
+}
+
+//responseTError convert err to terror and set result
+func responseTError(c *gin.Context, err error) *terror.TError {
+	te := responseTErrorGet(err)
+
+	responseResult(c, te)
+
+	return te
+}
+
+//responseTErrorGet err to TError,msg为空时使用code对应的msg
+func responseTErrorGet(err error) *terror.TError {
 	var te *terror.TError
 	var ok bool
 	if err == nil {
@@ -40,52 +165,52 @@ func ResponseJSONWithCallbackFlag(c *gin.Context, err error, model interface{},
 		}
 	}
 
+	if strings.Trim(te.Msg, " ") == "" {
+		te.Msg = config.CodeGetMsg(te.Code)
+	}
+	return te
+}
+
+//responseResult 记录请求的结果和code,每个请求只调用一次
+func responseResult(c *gin.Context, te *terror.TError) {
 	//添加结果
 	if te.Level == terror.LevelException {
-		c.Set("result", false)
+		c.Set(pconst.CONTEXT_KEY_RESULT, false)
 	} else {
-		c.Set("result", true)
+		c.Set(pconst.CONTEXT_KEY_RESULT, true)
 	}
+	c.Set(pconst.CONTEXT_KEY_CODE, te.Code)
 
-	if strings.Trim(te.Msg, " ") == "" {
-		te.Msg = config.CodeGetMsg(te.Code)
+	if config.FeatureMetrics() {
+		metrics.CodeInc(metricsSourceResponse, te.Code)
 	}
+}
 
+//responseBody response body with keys of config.Resp
+func responseBody(c *gin.Context, te *terror.TError, model interface{}) gin.H {
 	configResp := config.RespGet()
 
-	rj = gin.H{
+	h := gin.H{
 		configResp.Code: te.Code,
 		configResp.Msg:  te.GetMsg(),
 		configResp.Data: model,
 	}
 
-	var callback string
-	if callbackFlag {
-		callback = c.Query("callback")
+	if configResp.Details != "" && len(te.Details) > 0 {
+		h[configResp.Details] = gin.H(te.Details)
 	}
 
-	if strings.Trim(callback, " ") == "" {
-		c.Status(200)
+	responseHooksApply(c, te, h)
 
-		header := c.Writer.Header()
-		if val := header["Content-Type"]; len(val) == 0 {
-			header["Content-Type"] = []string{"application/json; charset=utf-8"}
-		}
+	return h
+}
 
-		encoder := json.NewEncoder(c.Writer)
-		encoder.SetEscapeHTML(false)
-		err := encoder.Encode(rj)
-		if err != nil {
-			panic(err)
-		}
-	} else {
-		b, err := responseJSONMarshal(rj)
-		if err != nil {
-			log.Errorf("jsonp marshal error:%s", err.Error())
-		} else {
-			c.String(200, "%s(%s)", callback, string(b))
-		}
+//responseStatus http status of terror, 200 if not configured
+func responseStatus(te *terror.TError) int {
+	if status := config.RespGetStatus(te.Code); status > 0 {
+		return status
 	}
+	return http.StatusOK
 }
 
 //responseJSONMarshal response json marshal
@@ -120,6 +245,10 @@ func ResponseGrpc(err error) (code int64, msg string) {
 		codeint = te.Code
 	}
 
+	if config.FeatureMetrics() {
+		metrics.CodeInc(metricsSourceResponse, codeint)
+	}
+
 	msg = config.CodeGetMsg(codeint)
 	code = int64(codeint)
 	return
@@ -0,0 +1,266 @@
+package tgo_v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"github.com/vmihailenco/msgpack"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	ResponseContentTypeJSON     = "application/json"
+	ResponseContentTypeProtobuf = "application/x-protobuf"
+	ResponseContentTypeMsgpack  = "application/x-msgpack"
+	ResponseContentTypeXML      = "application/xml"
+)
+
+//ResponseEnvelope envelope passed to encoder
+type ResponseEnvelope struct {
+	Code int
+	Msg  string
+	Data interface{}
+	Body gin.H //按config.Resp的key组装好的内容
+}
+
+//ResponseEncoder encode envelope to w
+type ResponseEncoder func(w io.Writer, envelope *ResponseEnvelope) error
+
+var (
+	//ErrResponseNotAcceptable encoder返回该错误时响应406,如data不能按Accept的格式编码
+	ErrResponseNotAcceptable = errors.New("response data is not acceptable")
+
+	responseEncoders      map[string]ResponseEncoder
+	responseEncoderOffers []string
+	responseEncoderMux    sync.RWMutex
+)
+
+func init() {
+	responseEncoders = make(map[string]ResponseEncoder)
+
+	ResponseRegisterEncoder(ResponseContentTypeJSON, responseEncodeJSON)
+	ResponseRegisterEncoder(ResponseContentTypeProtobuf, responseEncodeProtobuf)
+	ResponseRegisterEncoder(ResponseContentTypeMsgpack, responseEncodeMsgpack)
+	ResponseRegisterEncoder(ResponseContentTypeXML, responseEncodeXML)
+}
+
+//ResponseRegisterEncoder register encoder for content type,replace if exists
+func ResponseRegisterEncoder(contentType string, encoder ResponseEncoder) {
+	responseEncoderMux.Lock()
+	defer responseEncoderMux.Unlock()
+
+	if _, ok := responseEncoders[contentType]; !ok {
+		responseEncoderOffers = append(responseEncoderOffers, contentType)
+	}
+	responseEncoders[contentType] = encoder
+}
+
+//responseEncoderGet get encoder by Accept header, json if not matched
+func responseEncoderGet(c *gin.Context) (string, ResponseEncoder) {
+	responseEncoderMux.RLock()
+	defer responseEncoderMux.RUnlock()
+
+	contentType := c.NegotiateFormat(responseEncoderOffers...)
+
+	encoder, ok := responseEncoders[contentType]
+	if !ok {
+		return ResponseContentTypeJSON, responseEncoders[ResponseContentTypeJSON]
+	}
+	return contentType, encoder
+}
+
+//ResponseNegotiate response with encoder chosen by Accept header
+func ResponseNegotiate(c *gin.Context, err error, model interface{}) {
+	te := responseTError(c, err)
+
+	envelope := &ResponseEnvelope{Code: te.Code, Msg: te.GetMsg(), Data: model, Body: responseBody(c, te, model)}
+
+	status := 200
+	if config.RespGet().Status.Enable {
+		status = responseStatus(te)
+	}
+
+	contentType, encoder := responseEncoderGet(c)
+
+	c.Header("Vary", "Accept")
+
+	buffer := &bytes.Buffer{}
+	errEncode := encoder(buffer, envelope)
+	if errEncode != nil {
+		log.Errorf("response encode %s error:%s", contentType, errEncode.Error())
+
+		//不改用json,避免客户端按Accept的格式解析失败
+		if errEncode == ErrResponseNotAcceptable {
+			c.AbortWithStatus(http.StatusNotAcceptable)
+		} else {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Data(status, contentType, buffer.Bytes())
+}
+
+func responseEncodeJSON(w io.Writer, envelope *ResponseEnvelope) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(envelope.Body)
+}
+
+func responseEncodeMsgpack(w io.Writer, envelope *ResponseEnvelope) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.UseJSONTag(true)
+	return encoder.Encode(envelope.Body)
+}
+
+func responseEncodeXML(w io.Writer, envelope *ResponseEnvelope) error {
+	body, err := responseXMLGet(envelope.Body)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(body)
+}
+
+//responseXML map按keys的顺序输出,避免map遍历导致每次元素顺序不同
+type responseXML struct {
+	keys []string
+	body map[string]interface{}
+	root bool
+}
+
+//responseXMLList slice的每个元素输出为<item>
+type responseXMLList []interface{}
+
+//responseXMLGet 先按json转换,字段名和json一致,嵌套的map,slice,struct都可以输出;
+//顶层按code,msg,data,details,hooks的顺序,其余key和嵌套的map按字母序
+func responseXMLGet(body gin.H) (responseXML, error) {
+	b, err := responseJSONMarshal(body)
+	if err != nil {
+		return responseXML{}, err
+	}
+
+	var value map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(&value); err != nil {
+		return responseXML{}, err
+	}
+
+	configResp := config.RespGet()
+
+	keys := append([]string{configResp.Code, configResp.Msg, configResp.Data, configResp.Details}, responseHookKeys()...)
+
+	result := responseXMLSorted(value, keys)
+	result.root = true
+
+	return result, nil
+}
+
+func responseXMLSorted(body map[string]interface{}, keys []string) responseXML {
+	result := responseXML{body: body}
+
+	exists := make(map[string]bool)
+
+	for _, key := range keys {
+		if _, ok := body[key]; ok && !exists[key] {
+			exists[key] = true
+			result.keys = append(result.keys, key)
+		}
+	}
+
+	var others []string
+
+	for key := range body {
+		if !exists[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+
+	result.keys = append(result.keys, others...)
+
+	return result
+}
+
+//responseXMLValue json解析后的map和slice转换为可以按xml输出的类型
+func responseXMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return responseXMLSorted(v, nil)
+	case []interface{}:
+		return responseXMLList(v)
+	}
+	return value
+}
+
+func (r responseXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	//根元素同gin.H为map,嵌套的使用key
+	if r.root {
+		start.Name = xml.Name{Local: "map"}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, key := range r.keys {
+		if err := e.EncodeElement(responseXMLValue(r.body[key]), xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+func (l responseXMLList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, item := range l {
+		if err := e.EncodeElement(responseXMLValue(item), xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+//responseEncodeProtobuf data必须是proto.Message,否则返回ErrResponseNotAcceptable,编码格式:
+//message Response { int64 code = 1; string msg = 2; bytes data = 3; }
+//只有code,msg,data,TError.Details和hooks的值不输出
+func responseEncodeProtobuf(w io.Writer, envelope *ResponseEnvelope) error {
+	buffer := proto.NewBuffer(nil)
+
+	buffer.EncodeVarint(1<<3 | proto.WireVarint)
+	buffer.EncodeVarint(uint64(envelope.Code))
+
+	buffer.EncodeVarint(2<<3 | proto.WireBytes)
+	buffer.EncodeStringBytes(envelope.Msg)
+
+	if envelope.Data != nil {
+		message, ok := envelope.Data.(proto.Message)
+		if !ok {
+			return ErrResponseNotAcceptable
+		}
+		data, err := proto.Marshal(message)
+		if err != nil {
+			return err
+		}
+		buffer.EncodeVarint(3<<3 | proto.WireBytes)
+		buffer.EncodeRawBytes(data)
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
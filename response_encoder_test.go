@@ -0,0 +1,115 @@
+package tgo_v2
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testResponseNegotiate(accept string) *httptest.ResponseRecorder {
+	return testResponseNegotiateModel(accept, gin.H{"b": 2, "a": 1})
+}
+
+func testResponseNegotiateModel(accept string, model interface{}) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+
+	ResponseNegotiate(c, nil, model)
+
+	return w
+}
+
+func TestResponseNegotiate_JSON(t *testing.T) {
+	w := testResponseNegotiate(ResponseContentTypeJSON)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ResponseContentTypeJSON) {
+		t.Errorf("content type:%s", ct)
+	}
+
+	var body map[string]interface{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["data"] == nil || body["code"] == nil {
+		t.Errorf("body:%v", body)
+	}
+}
+
+func TestResponseNegotiate_XML(t *testing.T) {
+	w := testResponseNegotiate(ResponseContentTypeXML)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ResponseContentTypeXML) {
+		t.Errorf("content type:%s", ct)
+	}
+
+	body := w.Body.String()
+
+	if body != "<map><code>1001</code><msg>success</msg><data><a>1</a><b>2</b></data></map>" {
+		t.Errorf("xml should be ordered:%s", body)
+	}
+
+	//顺序固定
+	for i := 0; i < 10; i++ {
+		if other := testResponseNegotiate(ResponseContentTypeXML).Body.String(); other != body {
+			t.Fatalf("xml changed between responses:%s,%s", body, other)
+		}
+	}
+}
+
+func TestResponseNegotiate_XMLPage(t *testing.T) {
+	type item struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	list := []interface{}{item{Id: 1, Name: "a"}, map[string]interface{}{"id": 2}, []gin.H{{"x": 1}}}
+
+	w := testResponseNegotiateModel(ResponseContentTypeXML, ResponsePageCursor(list, nil, false))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status:%d,body:%s", w.Code, w.Body.String())
+	}
+
+	expected := "<map><code>1001</code><msg>success</msg><data><has_more>false</has_more><list>" +
+		"<item><id>1</id><name>a</name></item><item><id>2</id></item><item><item><x>1</x></item></item>" +
+		"</list></data></map>"
+
+	if body := w.Body.String(); body != expected {
+		t.Errorf("xml of page:%s", body)
+	}
+}
+
+func TestResponseNegotiate_Default(t *testing.T) {
+	for _, accept := range []string{"", "*/*", "text/html"} {
+		w := testResponseNegotiate(accept)
+
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ResponseContentTypeJSON) {
+			t.Errorf("accept %s content type:%s", accept, ct)
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("accept %s vary:%s", accept, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestResponseNegotiate_ProtobufNotAcceptable(t *testing.T) {
+	w := testResponseNegotiate(ResponseContentTypeProtobuf)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("data is not proto.Message,status should be 406:%d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("should not fall back to json:%s", w.Body.String())
+	}
+}
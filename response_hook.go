@@ -0,0 +1,134 @@
+package tgo_v2
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/terror"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"github.com/tonyjt/tgo_v2/zipkin"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	//ResponseRequestIdHeader request id header
+	ResponseRequestIdHeader = "X-Request-Id"
+
+	responseRequestIdKey = "request_id"
+
+	responseRequestIdMaxLen = 64
+)
+
+//ResponseHook value added to envelope,nil means not added
+type ResponseHook func(c *gin.Context, te *terror.TError) interface{}
+
+type responseHookItem struct {
+	key  string
+	hook ResponseHook
+}
+
+var (
+	responseRequestIdRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	responseHooks   []responseHookItem
+	responseHookMux sync.RWMutex
+)
+
+func init() {
+	conf := config.RespGet().Hooks
+
+	ResponseRegisterHook(conf.TraceId, responseHookTraceId)
+	ResponseRegisterHook(conf.RequestId, responseHookRequestId)
+	ResponseRegisterHook(conf.Time, responseHookTime)
+	ResponseRegisterHook(conf.Version, responseHookVersion)
+}
+
+//ResponseRegisterHook register hook,value of hook is added to envelope under key,ignored if key is empty
+func ResponseRegisterHook(key string, hook ResponseHook) {
+	if key == "" || hook == nil {
+		return
+	}
+	responseHookMux.Lock()
+	defer responseHookMux.Unlock()
+
+	responseHooks = append(responseHooks, responseHookItem{key: key, hook: hook})
+}
+
+//responseHookKeys keys of hooks in registration order
+func responseHookKeys() []string {
+	responseHookMux.RLock()
+	defer responseHookMux.RUnlock()
+
+	keys := make([]string, 0, len(responseHooks))
+
+	for _, item := range responseHooks {
+		keys = append(keys, item.key)
+	}
+	return keys
+}
+
+//responseHooksApply add values of hooks to h
+func responseHooksApply(c *gin.Context, te *terror.TError, h gin.H) {
+	responseHookMux.RLock()
+	defer responseHookMux.RUnlock()
+
+	for _, item := range responseHooks {
+		if value := item.hook(c, te); value != nil {
+			h[item.key] = value
+		}
+	}
+}
+
+//ResponseRequestId get request id from context or header,generate if not exists
+func ResponseRequestId(c *gin.Context) string {
+	if id := c.GetString(responseRequestIdKey); id != "" {
+		return id
+	}
+
+	id := c.GetHeader(ResponseRequestIdHeader)
+
+	if !responseRequestIdValid(id) {
+		id = tracing.RequestId(c.Request.Context())
+	}
+
+	//客户端传入的id会写到header和body中,不合法时重新生成
+	if !responseRequestIdValid(id) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+
+	c.Set(responseRequestIdKey, id)
+	c.Header(ResponseRequestIdHeader, id)
+
+	return id
+}
+
+func responseRequestIdValid(id string) bool {
+	return id != "" && len(id) <= responseRequestIdMaxLen && responseRequestIdRegexp.MatchString(id)
+}
+
+func responseHookTraceId(c *gin.Context, te *terror.TError) interface{} {
+	if id := zipkin.TraceId(c.Request.Context()); id != "" {
+		return id
+	}
+	return nil
+}
+
+func responseHookRequestId(c *gin.Context, te *terror.TError) interface{} {
+	return ResponseRequestId(c)
+}
+
+func responseHookTime(c *gin.Context, te *terror.TError) interface{} {
+	return time.Now().Unix()
+}
+
+func responseHookVersion(c *gin.Context, te *terror.TError) interface{} {
+	if version := config.AppGetString("Version", ""); version != "" {
+		return version
+	}
+	return nil
+}
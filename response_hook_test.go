@@ -0,0 +1,57 @@
+package tgo_v2
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/terror"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testResponseHookKey = "test_hook"
+
+func init() {
+	//只在context中有值时输出,不影响其他测试
+	ResponseRegisterHook(testResponseHookKey, func(c *gin.Context, te *terror.TError) interface{} {
+		if value := c.GetString(testResponseHookKey); value != "" {
+			return value
+		}
+		return nil
+	})
+	ResponseRegisterHook("", func(c *gin.Context, te *terror.TError) interface{} {
+		return "empty key"
+	})
+}
+
+func TestResponseHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+	c.Set(testResponseHookKey, "value")
+
+	ResponseJson(c, nil, nil)
+
+	var body map[string]interface{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body[testResponseHookKey] != "value" {
+		t.Errorf("hook value not added:%v", body)
+	}
+	//默认配置不开启内置的hook
+	if _, ok := body["request_id"]; ok {
+		t.Errorf("request id hook should be disabled by default:%v", body)
+	}
+	if _, ok := body[""]; ok {
+		t.Errorf("hook with empty key should be ignored:%v", body)
+	}
+
+	if keys := responseHookKeys(); keys[len(keys)-1] != testResponseHookKey {
+		t.Errorf("hook keys should be in registration order:%v", keys)
+	}
+}
@@ -0,0 +1,30 @@
+package tgo_v2
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+)
+
+//ResponsePageOffset page envelope for offset pagination,page starts from 1
+func ResponsePageOffset(list interface{}, total int, page int, size int) gin.H {
+	conf := config.RespGet().Page
+
+	return gin.H{
+		conf.List:    list,
+		conf.Total:   total,
+		conf.Page:    page,
+		conf.Size:    size,
+		conf.HasMore: page > 0 && size > 0 && page*size < total,
+	}
+}
+
+//ResponsePageCursor page envelope for cursor pagination
+func ResponsePageCursor(list interface{}, cursor interface{}, hasMore bool) gin.H {
+	conf := config.RespGet().Page
+
+	return gin.H{
+		conf.List:    list,
+		conf.Cursor:  cursor,
+		conf.HasMore: hasMore,
+	}
+}
@@ -0,0 +1,112 @@
+package tgo_v2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//ResponseChunk chunk of stream response
+type ResponseChunk struct {
+	Event string //sse的event,会去掉\r和\n,ndjson忽略
+	Err   error
+	Data  interface{}
+}
+
+var responseSSEEventReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+//ResponseSSE write chunks as server-sent events until chunks is closed or client is disconnected,
+//heartbeat > 0 writes a comment line every heartbeat,return ctx error if client is disconnected
+func ResponseSSE(c *gin.Context, chunks <-chan *ResponseChunk, heartbeat time.Duration) error {
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+
+	return responseStream(c, chunks, heartbeat, func(chunk *ResponseChunk, b []byte) string {
+		//换行会结束当前field,event中的换行可以注入其他field和event
+		if event := responseSSEEventReplacer.Replace(chunk.Event); event != "" {
+			return fmt.Sprintf("event: %s\ndata: %s\n\n", event, b)
+		}
+		return fmt.Sprintf("data: %s\n\n", b)
+	}, ": heartbeat\n\n")
+}
+
+//ResponseNDJSON write chunks as newline-delimited json until chunks is closed or client is disconnected,
+//heartbeat > 0 writes an empty line every heartbeat,return ctx error if client is disconnected
+func ResponseNDJSON(c *gin.Context, chunks <-chan *ResponseChunk, heartbeat time.Duration) error {
+	header := c.Writer.Header()
+	header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+
+	return responseStream(c, chunks, heartbeat, func(chunk *ResponseChunk, b []byte) string {
+		return fmt.Sprintf("%s\n", b)
+	}, "\n")
+}
+
+//responseStream stream loop,each chunk is wrapped in envelope and flushed
+func responseStream(c *gin.Context, chunks <-chan *ResponseChunk, heartbeat time.Duration,
+	format func(chunk *ResponseChunk, b []byte) string, beat string) error {
+
+	ctx := c.Request.Context()
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	//整个stream只记录一次结果,以第一个出错的chunk为准
+	var failed *terror.TError
+	defer func() {
+		if failed == nil {
+			failed = terror.New(pconst.ERROR_OK)
+		}
+		responseResult(c, failed)
+	}()
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		var line string
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			line = beat
+		case chunk, ok := <-chunks:
+			if !ok {
+				return nil
+			}
+			if chunk == nil {
+				continue
+			}
+			te := responseTErrorGet(chunk.Err)
+			if chunk.Err != nil && failed == nil {
+				failed = te
+			}
+
+			b, err := responseJSONMarshal(responseBody(c, te, chunk.Data))
+			if err != nil {
+				return err
+			}
+			line = format(chunk, bytes.TrimRight(b, "\n"))
+		}
+
+		if _, err := c.Writer.WriteString(line); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+	}
+}
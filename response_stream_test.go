@@ -0,0 +1,127 @@
+package tgo_v2
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/terror"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResponseStream(stream func(c *gin.Context, chunks <-chan *ResponseChunk, heartbeat time.Duration) error,
+	chunks []*ResponseChunk) (*httptest.ResponseRecorder, error) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+	c.Request.Header.Set(ResponseRequestIdHeader, "test")
+
+	ch := make(chan *ResponseChunk, len(chunks))
+	for _, chunk := range chunks {
+		ch <- chunk
+	}
+	close(ch)
+
+	return w, stream(c, ch, 0)
+}
+
+func TestResponseSSE(t *testing.T) {
+	w, err := testResponseStream(ResponseSSE, []*ResponseChunk{
+		{Event: "add", Data: 1},
+		nil,
+		{Err: errors.New("failed")},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Errorf("content type:%s", ct)
+	}
+
+	events := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+
+	if len(events) != 2 {
+		t.Fatalf("events:%q", w.Body.String())
+	}
+	if !strings.HasPrefix(events[0], "event: add\ndata: {") || !strings.Contains(events[0], `"data":1`) {
+		t.Errorf("event:%q", events[0])
+	}
+	if !strings.HasPrefix(events[1], "data: {") || strings.Contains(events[1], `"code":1001`) {
+		t.Errorf("error event:%q", events[1])
+	}
+}
+
+func TestResponseSSE_EventNewline(t *testing.T) {
+	w, err := testResponseStream(ResponseSSE, []*ResponseChunk{{Event: "add\r\ndata: injected", Data: 1}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "event: adddata: injected\ndata: {") {
+		t.Errorf("newline in event should be removed:%q", body)
+	}
+}
+
+func TestResponseStream_Result(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	ch := make(chan *ResponseChunk, 3)
+	ch <- &ResponseChunk{Err: terror.New(pconst.ERROR_MONGO_FIND)}
+	ch <- &ResponseChunk{Data: 1}
+	ch <- &ResponseChunk{Err: errors.New("failed")}
+	close(ch)
+
+	if err := ResponseNDJSON(c, ch, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	//以第一个出错的chunk为准,后面成功的chunk不覆盖
+	if result, _ := c.Get(pconst.CONTEXT_KEY_RESULT); result != false {
+		t.Errorf("result should be false:%v", result)
+	}
+	if code, _ := c.Get(pconst.CONTEXT_KEY_CODE); code != pconst.ERROR_MONGO_FIND {
+		t.Errorf("code should be %d:%v", pconst.ERROR_MONGO_FIND, code)
+	}
+}
+
+func TestResponseNDJSON(t *testing.T) {
+	w, err := testResponseStream(ResponseNDJSON, []*ResponseChunk{{Data: 1}, {Data: 2}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/x-ndjson") {
+		t.Errorf("content type:%s", ct)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[0], `"data":1`) || !strings.Contains(lines[1], `"data":2`) {
+		t.Errorf("lines:%q", w.Body.String())
+	}
+}
+
+func TestResponseStream_Disconnected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx)
+	cancel()
+
+	if err := ResponseNDJSON(c, make(chan *ResponseChunk), time.Millisecond); err == nil {
+		t.Error("disconnected client should return ctx error")
+	}
+}
@@ -0,0 +1,115 @@
+package tgo_v2
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testResponseJsonp(origins []string, callback string, referer string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	conf := config.RespGet()
+	originsBefore := conf.Jsonp.Origins
+	conf.Jsonp.Origins = origins
+	defer func() {
+		conf.Jsonp.Origins = originsBefore
+	}()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest(http.MethodGet, "/test?callback="+callback, nil)
+	if referer != "" {
+		c.Request.Header.Set("Referer", referer)
+	}
+
+	ResponseJson(c, nil, gin.H{"a": 1})
+
+	return w
+}
+
+func TestResponseJsonpCallback(t *testing.T) {
+	cases := map[string]bool{
+		"cb":                     true,
+		"$.jsonp_1":              true,
+		"a.b.c":                  true,
+		"alert(1)":               false,
+		"1cb":                    false,
+		"cb;alert":               false,
+		"a..b":                   false,
+		strings.Repeat("a", 129): false,
+		"<script>":               false,
+	}
+
+	for callback, allowed := range cases {
+		body := testResponseJsonp(nil, callback, "").Body.String()
+
+		if strings.HasPrefix(body, "/**/"+callback+"(") != allowed {
+			t.Errorf("callback %s allowed should be %v:%s", callback, allowed, body)
+		}
+	}
+}
+
+func TestResponseJsonpOrigins(t *testing.T) {
+	origins := []string{"https://m.example.com", "*.example.org", "http://*.example.net"}
+
+	cases := map[string]bool{
+		"https://m.example.com/a":   true,
+		"http://m.example.com/a":    false,
+		"https://a.example.org/":    true,
+		"http://a.example.org/":     false,
+		"https://a.example.org.cn/": false,
+		"http://a.example.net/":     true,
+		"https://a.example.net/":    false,
+		"https://evil.com/":         false,
+		"":                          false,
+	}
+
+	for referer, allowed := range cases {
+		w := testResponseJsonp(origins, "cb", referer)
+
+		isJsonp := strings.HasPrefix(w.Body.String(), "/**/cb(")
+
+		if isJsonp != allowed {
+			t.Errorf("referer %s allowed should be %v:%s", referer, allowed, w.Body.String())
+		}
+		if !isJsonp && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("referer %s denied should response json:%s", referer, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestResponseRequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := map[string]bool{
+		"abc-123_x.y":           true,
+		"":                      false,
+		"a b":                   false,
+		"<script>":              false,
+		"id\r\nSet-Cookie: a=1": false,
+		strings.Repeat("a", 64): true,
+		strings.Repeat("a", 65): false,
+	}
+
+	for header, kept := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+		c.Request.Header[ResponseRequestIdHeader] = []string{header}
+
+		id := ResponseRequestId(c)
+
+		if (id == header) != kept {
+			t.Errorf("header %q kept should be %v:%s", header, kept, id)
+		}
+		if id == "" || w.Header().Get(ResponseRequestIdHeader) != id {
+			t.Errorf("header %q response id:%s,header:%s", header, id, w.Header().Get(ResponseRequestIdHeader))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package terror
 
 import (
+	"errors"
 	"fmt"
 	"github.com/tonyjt/tgo_v2/pconst"
 )
@@ -10,6 +11,8 @@ type TError struct {
 	Msg       string
 	Level     Level
 	MsgCustom string
+	Details   map[string]interface{} //附加信息,如字段校验错误,资源id
+	Retryable bool                   //是否可以重试
 }
 
 type Level int8
@@ -33,7 +36,7 @@ func NewFromError(err error) *TError {
 	if err == nil {
 		return nil
 	}
-	return &TError{Code: pconst.ERROR_SYSTEM, Msg: err.Error(), Level: LevelException}
+	return &TError{Code: pconst.ERROR_SYSTEM, Msg: err.Error(), Level: LevelException, Retryable: IsRetryable(err)}
 }
 
 func (p *TError) GetMsg() string {
@@ -53,3 +56,49 @@ func (p *TError) GetMsg() string {
 func (p *TError) Error() string {
 	return p.GetMsg()
 }
+
+//SetDetail set detail key/value
+func (p *TError) SetDetail(key string, value interface{}) *TError {
+	if p.Details == nil {
+		p.Details = make(map[string]interface{})
+	}
+	p.Details[key] = value
+	return p
+}
+
+//GetDetail get detail by key
+func (p *TError) GetDetail(key string) (value interface{}, ok bool) {
+	value, ok = p.Details[key]
+	return
+}
+
+//SetRetryable set retryable
+func (p *TError) SetRetryable(retryable bool) *TError {
+	p.Retryable = retryable
+	return p
+}
+
+//Temporary same as net.Error,true if retryable
+func (p *TError) Temporary() bool {
+	return p.Retryable
+}
+
+//IsRetryable whether err can be retried, TError by Retryable, others by Temporary() or Timeout(),包装过的err按errors.As判断
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var te *TError
+	if errors.As(err, &te) {
+		return te.Retryable
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	return false
+}
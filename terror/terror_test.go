@@ -0,0 +1,74 @@
+package terror
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"testing"
+)
+
+type testNetError struct {
+	timeout   bool
+	temporary bool
+}
+
+func (e testNetError) Error() string   { return "net error" }
+func (e testNetError) Timeout() bool   { return e.timeout }
+func (e testNetError) Temporary() bool { return e.temporary }
+
+func TestTError_Detail(t *testing.T) {
+	err := New(pconst.ERROR_SYSTEM)
+
+	if _, ok := err.GetDetail("id"); ok {
+		t.Error("detail should not exist")
+	}
+
+	err.SetDetail("id", 1).SetDetail("field", "name")
+
+	if value, ok := err.GetDetail("id"); !ok || value != 1 {
+		t.Errorf("detail id:%v,%v", value, ok)
+	}
+	if len(err.Details) != 2 {
+		t.Errorf("details:%v", err.Details)
+	}
+}
+
+func TestTError_Retryable(t *testing.T) {
+	err := New(pconst.ERROR_SYSTEM)
+
+	if err.Temporary() || IsRetryable(err) {
+		t.Error("new TError should not be retryable")
+	}
+
+	err.SetRetryable(true)
+
+	if !err.Temporary() || !IsRetryable(err) {
+		t.Error("TError should be retryable after SetRetryable")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{errors.New("config error"), false},
+		{testNetError{timeout: true}, true},
+		{testNetError{temporary: true}, true},
+		{testNetError{}, false},
+		{New(pconst.ERROR_SYSTEM).SetRetryable(false), false},
+		{fmt.Errorf("wrapped:%w", New(pconst.ERROR_SYSTEM).SetRetryable(true)), true},
+		{fmt.Errorf("wrapped:%w", testNetError{timeout: true}), true},
+	}
+
+	for i, c := range cases {
+		if IsRetryable(c.err) != c.retryable {
+			t.Errorf("case %d:%v,expected retryable:%v", i, c.err, c.retryable)
+		}
+	}
+
+	if te := NewFromError(testNetError{timeout: true}); !te.Retryable {
+		t.Error("NewFromError should keep retryable of timeout error")
+	}
+}
@@ -0,0 +1,229 @@
+package tracing
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tonyjt/tgo_v2/log"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	BaggageUserId    = "user_id"
+	BaggageTenantId  = "tenant_id"
+	BaggageRequestId = "request_id"
+
+	//HeaderBaggage W3C baggage header,grpc metadata也使用此key
+	HeaderBaggage = "baggage"
+
+	//W3C建议的上限,超出部分丢弃,避免客户端传入过大的header一路传递下去
+	baggageMaxItems = 64
+	baggageMaxLen   = 8192
+)
+
+type baggageKey struct{}
+
+func init() {
+	log.RegisterContextFields(baggageLogFields)
+}
+
+//SetBaggage set baggage item to span in ctx,没有span时也保存在ctx中
+func SetBaggage(ctx context.Context, key string, value string) context.Context {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetBaggageItem(key, value)
+	}
+
+	items := make(map[string]string)
+	if old, ok := ctx.Value(baggageKey{}).(map[string]string); ok {
+		for k, v := range old {
+			items[k] = v
+		}
+	}
+	items[key] = value
+
+	return context.WithValue(ctx, baggageKey{}, items)
+}
+
+//Baggage baggage item in ctx,empty if not exists
+func Baggage(ctx context.Context, key string) string {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if value := span.BaggageItem(key); value != "" {
+			return value
+		}
+	}
+	if items, ok := ctx.Value(baggageKey{}).(map[string]string); ok {
+		return items[key]
+	}
+	return ""
+}
+
+//Baggages all baggage items in ctx
+func Baggages(ctx context.Context) map[string]string {
+	items := make(map[string]string)
+
+	if old, ok := ctx.Value(baggageKey{}).(map[string]string); ok {
+		for k, v := range old {
+			items[k] = v
+		}
+	}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.Context().ForeachBaggageItem(func(k, v string) bool {
+			items[k] = v
+			return true
+		})
+	}
+	return items
+}
+
+//DropBaggage remove baggage items from ctx,span中的置为空,如去掉不可信的上游通过tracer header传入的user id
+func DropBaggage(ctx context.Context, keys ...string) context.Context {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		for _, key := range keys {
+			if span.BaggageItem(key) != "" {
+				span.SetBaggageItem(key, "")
+			}
+		}
+	}
+
+	old, ok := ctx.Value(baggageKey{}).(map[string]string)
+	if !ok {
+		return ctx
+	}
+
+	items := make(map[string]string)
+	for k, v := range old {
+		items[k] = v
+	}
+	for _, key := range keys {
+		delete(items, key)
+	}
+	return context.WithValue(ctx, baggageKey{}, items)
+}
+
+//SetUserId set user id baggage
+func SetUserId(ctx context.Context, userId string) context.Context {
+	return SetBaggage(ctx, BaggageUserId, userId)
+}
+
+//UserId user id baggage
+func UserId(ctx context.Context) string {
+	return Baggage(ctx, BaggageUserId)
+}
+
+//SetTenantId set tenant id baggage
+func SetTenantId(ctx context.Context, tenantId string) context.Context {
+	return SetBaggage(ctx, BaggageTenantId, tenantId)
+}
+
+//TenantId tenant id baggage
+func TenantId(ctx context.Context) string {
+	return Baggage(ctx, BaggageTenantId)
+}
+
+//SetRequestId set request id baggage
+func SetRequestId(ctx context.Context, requestId string) context.Context {
+	return SetBaggage(ctx, BaggageRequestId, requestId)
+}
+
+//RequestId request id baggage
+func RequestId(ctx context.Context) string {
+	return Baggage(ctx, BaggageRequestId)
+}
+
+//InjectBaggage set W3C baggage header,tracer为noop时也能传递
+func InjectBaggage(ctx context.Context, header http.Header) {
+	if value := baggageEncode(Baggages(ctx)); value != "" {
+		header.Set(HeaderBaggage, value)
+	}
+}
+
+//ExtractBaggage set baggage items of W3C baggage header to ctx
+func ExtractBaggage(ctx context.Context, header http.Header) context.Context {
+	for _, value := range header[http.CanonicalHeaderKey(HeaderBaggage)] {
+		for k, v := range baggageDecode(value) {
+			ctx = SetBaggage(ctx, k, v)
+		}
+	}
+	return ctx
+}
+
+//InjectBaggageGrpc append baggage to outgoing grpc metadata
+func InjectBaggageGrpc(ctx context.Context) context.Context {
+	if value := baggageEncode(Baggages(ctx)); value != "" {
+		return metadata.AppendToOutgoingContext(ctx, HeaderBaggage, value)
+	}
+	return ctx
+}
+
+//ExtractBaggageGrpc set baggage items of incoming grpc metadata to ctx
+func ExtractBaggageGrpc(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+
+	if !ok {
+		return ctx
+	}
+	for _, value := range md.Get(HeaderBaggage) {
+		for k, v := range baggageDecode(value) {
+			ctx = SetBaggage(ctx, k, v)
+		}
+	}
+	return ctx
+}
+
+//baggageEncode key1=value1,key2=value2
+func baggageEncode(items map[string]string) string {
+	var parts []string
+
+	for k, v := range items {
+		if v == "" {
+			continue
+		}
+		//PathEscape不转义=,key中的=需要转义
+		parts = append(parts, strings.Replace(url.PathEscape(k), "=", "%3D", -1)+"="+url.PathEscape(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+func baggageDecode(value string) map[string]string {
+	items := make(map[string]string)
+
+	if len(value) > baggageMaxLen {
+		return items
+	}
+	for _, part := range strings.Split(value, ",") {
+		if len(items) >= baggageMaxItems {
+			break
+		}
+		//忽略;后的属性
+		if i := strings.Index(part, ";"); i >= 0 {
+			part = part[:i]
+		}
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k, errKey := url.PathUnescape(strings.TrimSpace(kv[0]))
+		v, errValue := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if errKey != nil || errValue != nil || k == "" {
+			continue
+		}
+		items[k] = v
+	}
+	return items
+}
+
+func baggageLogFields(ctx context.Context) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	if id := TraceId(ctx); id != "" {
+		fields["trace_id"] = id
+	}
+	for _, key := range []string{BaggageUserId, BaggageTenantId, BaggageRequestId} {
+		if value := Baggage(ctx, key); value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
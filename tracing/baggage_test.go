@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBaggage(t *testing.T) {
+	ctx := SetUserId(context.Background(), "10001")
+	ctx = SetTenantId(ctx, "t 1,2")
+
+	header := http.Header{}
+	InjectBaggage(ctx, header)
+
+	ctx2 := ExtractBaggage(context.Background(), header)
+
+	if UserId(ctx2) != "10001" || TenantId(ctx2) != "t 1,2" {
+		t.Errorf("baggage header:%s,user id:%s,tenant id:%s", header.Get(HeaderBaggage), UserId(ctx2), TenantId(ctx2))
+	}
+
+	if RequestId(ctx2) != "" {
+		t.Errorf("request id should be empty")
+	}
+
+	items := baggageDecode("user_id=1;prop=1, request_id = abc,invalid")
+	if items[BaggageUserId] != "1" || items[BaggageRequestId] != "abc" || len(items) != 2 {
+		t.Errorf("decode:%v", items)
+	}
+
+	items = baggageDecode(baggageEncode(map[string]string{"a=b": "c=d"}))
+	if items["a=b"] != "c=d" || len(items) != 1 {
+		t.Errorf("decode key with =:%v", items)
+	}
+
+	var parts []string
+	for i := 0; i < baggageMaxItems+10; i++ {
+		parts = append(parts, fmt.Sprintf("k%d=v", i))
+	}
+	if items = baggageDecode(strings.Join(parts, ",")); len(items) != baggageMaxItems {
+		t.Errorf("items should be limited to %d:%d", baggageMaxItems, len(items))
+	}
+
+	if items = baggageDecode("user_id=" + strings.Repeat("1", baggageMaxLen)); len(items) != 0 {
+		t.Errorf("too long baggage should be ignored:%d", len(items))
+	}
+
+	ctx = DropBaggage(ctx, BaggageUserId)
+	if UserId(ctx) != "" || TenantId(ctx) != "t 1,2" {
+		t.Errorf("user id should be dropped,user id:%s,tenant id:%s", UserId(ctx), TenantId(ctx))
+	}
+}
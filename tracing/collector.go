@@ -0,0 +1,171 @@
+package tracing
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+	"github.com/tonyjt/tgo_v2/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	collectorBatchSize     = 100
+	collectorBatchInterval = time.Second
+	collectorMaxBacklog    = 1000
+	collectorTimeout       = 5 * time.Second
+)
+
+//spanSender send a batch of spans to backend
+type spanSender func(spans []*zipkincore.Span) error
+
+//batchCollector zipkintracer.Collector,Collect只入队,由loop按批量或间隔发送,backlog满了丢弃新的span
+type batchCollector struct {
+	send    spanSender
+	batch   []*zipkincore.Span
+	dropped int
+	mux     sync.Mutex
+	signal  chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+func newBatchCollector(send spanSender) *batchCollector {
+	c := &batchCollector{
+		send:    send,
+		signal:  make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go c.loop()
+
+	return c
+}
+
+//Collect add span to batch,不阻塞span.Finish
+func (c *batchCollector) Collect(span *zipkincore.Span) error {
+	c.mux.Lock()
+	if len(c.batch) >= collectorMaxBacklog {
+		c.dropped++
+		c.mux.Unlock()
+		return nil
+	}
+	c.batch = append(c.batch, span)
+	full := len(c.batch) >= collectorBatchSize
+	c.mux.Unlock()
+
+	if full {
+		select {
+		case c.signal <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+//Close flush remaining spans and stop
+func (c *batchCollector) Close() error {
+	select {
+	case <-c.quit:
+		return nil
+	default:
+		close(c.quit)
+	}
+	<-c.stopped
+
+	return c.flush()
+}
+
+func (c *batchCollector) loop() {
+	ticker := time.NewTicker(collectorBatchInterval)
+	defer ticker.Stop()
+	defer close(c.stopped)
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.signal:
+			c.flush()
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+//flush send all spans in batches of collectorBatchSize
+func (c *batchCollector) flush() (err error) {
+	c.mux.Lock()
+	spans, dropped := c.batch, c.dropped
+	c.batch, c.dropped = nil, 0
+	c.mux.Unlock()
+
+	if dropped > 0 {
+		log.Errorf("tracing collector backlog is full,%d spans dropped", dropped)
+	}
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > collectorBatchSize {
+			n = collectorBatchSize
+		}
+
+		if errSend := c.send(spans[:n]); errSend != nil {
+			log.Errorf("tracing collector send %d spans failed: %s", n, errSend.Error())
+			err = errSend
+		}
+		spans = spans[n:]
+	}
+	return err
+}
+
+//httpSender post encoded spans to endpoint
+func httpSender(endpoint string, contentType string, encode func(spans []*zipkincore.Span) ([]byte, error)) spanSender {
+	client := &http.Client{Timeout: collectorTimeout}
+
+	return func(spans []*zipkincore.Span) error {
+		body, err := encode(spans)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Post(endpoint, contentType, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		io.Copy(ioutil.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("collector %s response status %d", endpoint, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+//writerSender write spans as zipkin v2 json lines
+func writerSender(w io.Writer) spanSender {
+	var mux sync.Mutex
+
+	return func(spans []*zipkincore.Span) error {
+		var buf bytes.Buffer
+
+		for _, span := range spans {
+			line, err := zipkinV2Encode([]*zipkincore.Span{span})
+			if err != nil {
+				return err
+			}
+			//去掉数组的[]
+			buf.Write(line[1 : len(line)-1])
+			buf.WriteByte('\n')
+		}
+		mux.Lock()
+		defer mux.Unlock()
+
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+}
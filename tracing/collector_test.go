@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchCollector(t *testing.T) {
+	var (
+		mux     sync.Mutex
+		batches []int
+	)
+	block := make(chan struct{})
+
+	c := newBatchCollector(func(spans []*zipkincore.Span) error {
+		<-block
+
+		mux.Lock()
+		batches = append(batches, len(spans))
+		mux.Unlock()
+		return nil
+	})
+
+	start := time.Now()
+
+	//sender阻塞时Collect也不阻塞,超过backlog的丢弃
+	for i := 0; i < collectorMaxBacklog*2; i++ {
+		c.Collect(&zipkincore.Span{})
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("collect should not block:%s", time.Since(start))
+	}
+
+	close(block)
+	c.Close()
+
+	var total int
+	for _, n := range batches {
+		if n > collectorBatchSize {
+			t.Errorf("batch size %d exceeds %d", n, collectorBatchSize)
+		}
+		total += n
+	}
+	//loop取走一批后backlog可以再满一次
+	if total < collectorMaxBacklog || total > collectorMaxBacklog*2 {
+		t.Errorf("sent %d spans,batches:%v", total, batches)
+	}
+}
@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+	"net"
+	"strconv"
+)
+
+type zipkinV2Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	Ipv4        string `json:"ipv4,omitempty"`
+	Ipv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+type zipkinV2Annotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+type zipkinV2Span struct {
+	TraceId        string               `json:"traceId"`
+	Id             string               `json:"id"`
+	ParentId       string               `json:"parentId,omitempty"`
+	Name           string               `json:"name,omitempty"`
+	Kind           string               `json:"kind,omitempty"`
+	Timestamp      int64                `json:"timestamp,omitempty"`
+	Duration       int64                `json:"duration,omitempty"`
+	Debug          bool                 `json:"debug,omitempty"`
+	Shared         bool                 `json:"shared,omitempty"`
+	LocalEndpoint  *zipkinV2Endpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *zipkinV2Endpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []zipkinV2Annotation `json:"annotations,omitempty"`
+	Tags           map[string]string    `json:"tags,omitempty"`
+}
+
+//spanInfo v1 span中解析出的通用信息,zipkin v2和otlp共用
+type spanInfo struct {
+	traceId     string
+	id          string
+	parentId    string
+	kind        string //CLIENT,SERVER,空为local
+	timestamp   int64  //微秒
+	duration    int64  //微秒
+	local       *zipkinV2Endpoint
+	remote      *zipkinV2Endpoint
+	annotations []zipkinV2Annotation
+	tags        map[string]string
+}
+
+func spanInfoGet(span *zipkincore.Span) *spanInfo {
+	info := &spanInfo{
+		traceId: spanHexId(span.TraceID),
+		id:      spanHexId(span.ID),
+		tags:    make(map[string]string),
+	}
+	if span.TraceIDHigh != nil && *span.TraceIDHigh != 0 {
+		info.traceId = spanHexId(*span.TraceIDHigh) + info.traceId
+	}
+	if span.ParentID != nil {
+		info.parentId = spanHexId(*span.ParentID)
+	}
+	if span.Timestamp != nil {
+		info.timestamp = *span.Timestamp
+	}
+	if span.Duration != nil {
+		info.duration = *span.Duration
+	}
+
+	for _, a := range span.Annotations {
+		switch a.Value {
+		case zipkincore.CLIENT_SEND, zipkincore.CLIENT_RECV:
+			info.kind = "CLIENT"
+		case zipkincore.SERVER_RECV, zipkincore.SERVER_SEND:
+			info.kind = "SERVER"
+		default:
+			info.annotations = append(info.annotations, zipkinV2Annotation{Timestamp: a.Timestamp, Value: a.Value})
+		}
+		if info.local == nil && a.Host != nil {
+			info.local = spanEndpoint(a.Host)
+		}
+	}
+
+	for _, b := range span.BinaryAnnotations {
+		//sa,ca为对端地址
+		if b.AnnotationType == zipkincore.AnnotationType_BOOL && (b.Key == "sa" || b.Key == "ca") {
+			if b.Host != nil {
+				info.remote = spanEndpoint(b.Host)
+			}
+			continue
+		}
+		if info.local == nil && b.Host != nil {
+			info.local = spanEndpoint(b.Host)
+		}
+		info.tags[b.Key] = spanBinaryValue(b)
+	}
+	return info
+}
+
+//zipkinV2Encode encode v1 spans to zipkin v2 json
+func zipkinV2Encode(spans []*zipkincore.Span) ([]byte, error) {
+	v2Spans := make([]*zipkinV2Span, 0, len(spans))
+
+	for _, span := range spans {
+		info := spanInfoGet(span)
+
+		v2Span := &zipkinV2Span{
+			TraceId:        info.traceId,
+			Id:             info.id,
+			ParentId:       info.parentId,
+			Name:           span.Name,
+			Kind:           info.kind,
+			Timestamp:      info.timestamp,
+			Duration:       info.duration,
+			Debug:          span.Debug,
+			LocalEndpoint:  info.local,
+			RemoteEndpoint: info.remote,
+			Annotations:    info.annotations,
+		}
+		if len(info.tags) > 0 {
+			v2Span.Tags = info.tags
+		}
+		//SameSpan时server端span没有timestamp
+		if info.kind == "SERVER" && span.Timestamp == nil {
+			v2Span.Shared = true
+		}
+		v2Spans = append(v2Spans, v2Span)
+	}
+	return json.Marshal(v2Spans)
+}
+
+func spanHexId(id int64) string {
+	return fmt.Sprintf("%016x", uint64(id))
+}
+
+func spanEndpoint(e *zipkincore.Endpoint) *zipkinV2Endpoint {
+	endpoint := &zipkinV2Endpoint{
+		ServiceName: e.ServiceName,
+		Port:        int(uint16(e.Port)),
+	}
+	if e.Ipv4 != 0 {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, uint32(e.Ipv4))
+		endpoint.Ipv4 = ip.String()
+	}
+	if len(e.Ipv6) == net.IPv6len {
+		endpoint.Ipv6 = net.IP(e.Ipv6).String()
+	}
+	return endpoint
+}
+
+func spanBinaryValue(b *zipkincore.BinaryAnnotation) string {
+	switch b.AnnotationType {
+	case zipkincore.AnnotationType_BOOL:
+		return strconv.FormatBool(len(b.Value) > 0 && b.Value[0] == 1)
+	case zipkincore.AnnotationType_I16:
+		if len(b.Value) == 2 {
+			return strconv.FormatInt(int64(int16(binary.BigEndian.Uint16(b.Value))), 10)
+		}
+	case zipkincore.AnnotationType_I32:
+		if len(b.Value) == 4 {
+			return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(b.Value))), 10)
+		}
+	case zipkincore.AnnotationType_I64:
+		if len(b.Value) == 8 {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(b.Value)), 10)
+		}
+	}
+	return string(b.Value)
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"encoding/json"
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+	"testing"
+)
+
+func TestZipkinV2Encode(t *testing.T) {
+	parentId := int64(1)
+	timestamp := int64(1500000000000000)
+	duration := int64(1000)
+	host := &zipkincore.Endpoint{Ipv4: 0x7f000001, Port: 8080, ServiceName: "tgov2"}
+
+	span := &zipkincore.Span{
+		TraceID:   10,
+		ID:        2,
+		ParentID:  &parentId,
+		Name:      "get",
+		Timestamp: &timestamp,
+		Duration:  &duration,
+		Annotations: []*zipkincore.Annotation{
+			{Timestamp: timestamp, Value: zipkincore.CLIENT_SEND, Host: host},
+			{Timestamp: timestamp + duration, Value: zipkincore.CLIENT_RECV, Host: host},
+		},
+		BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+			{Key: "http.url", Value: []byte("/test"), AnnotationType: zipkincore.AnnotationType_STRING, Host: host},
+		},
+	}
+
+	data, err := zipkinV2Encode([]*zipkincore.Span{span})
+	if err != nil {
+		t.Fatalf("encode error:%s", err.Error())
+	}
+
+	var spans []*zipkinV2Span
+	if err = json.Unmarshal(data, &spans); err != nil || len(spans) != 1 {
+		t.Fatalf("decode error:%s", string(data))
+	}
+	s := spans[0]
+
+	if s.TraceId != "000000000000000a" || s.ParentId != "0000000000000001" || s.Kind != "CLIENT" {
+		t.Errorf("span:%s", string(data))
+	}
+	if s.LocalEndpoint == nil || s.LocalEndpoint.Ipv4 != "127.0.0.1" || s.Tags["http.url"] != "/test" {
+		t.Errorf("span:%s", string(data))
+	}
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/uber/jaeger-client-go"
+	jaegerconfig "github.com/uber/jaeger-client-go/config"
+	"github.com/uber/jaeger-client-go/zipkin"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(BackendJaeger, jaegerFactory)
+
+	RegisterTraceId(jaegerTraceId)
+}
+
+//jaegerFactory CollectorEndpoint如 http://jaeger-collector:14268/api/traces
+func jaegerFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	cfg := jaegerconfig.Configuration{
+		ServiceName: conf.ServiceName,
+		Sampler: &jaegerconfig.SamplerConfig{
+			Type:  jaegerSamplerType(conf.Sampler.Type),
+			Param: conf.Sampler.Param,
+		},
+		Reporter: &jaegerconfig.ReporterConfig{
+			LogSpans:          conf.Debug,
+			CollectorEndpoint: conf.CollectorEndpoint,
+		},
+		Tags: []opentracing.Tag{{Key: "hostPort", Value: hostPort}},
+	}
+
+	//http header使用B3,和zipkin backend的服务互通
+	propagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
+
+	return cfg.NewTracer(
+		jaegerconfig.Gen128Bit(conf.TraceID128Bit),
+		jaegerconfig.Injector(opentracing.HTTPHeaders, propagator),
+		jaegerconfig.Extractor(opentracing.HTTPHeaders, propagator),
+		jaegerconfig.Extractor(opentracing.TextMap, propagator),
+	)
+}
+
+func jaegerSamplerType(samplerType string) string {
+	switch strings.ToLower(samplerType) {
+	case SamplerProbabilistic:
+		return jaeger.SamplerTypeProbabilistic
+	case SamplerRateLimiting:
+		return jaeger.SamplerTypeRateLimiting
+	default:
+		return jaeger.SamplerTypeConst
+	}
+}
+
+func jaegerTraceId(ctx context.Context, span opentracing.Span) string {
+	if sc, ok := span.Context().(jaeger.SpanContext); ok {
+		return sc.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"encoding/json"
+	"github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go-opentracing/thrift/gen-go/zipkincore"
+	"github.com/tonyjt/tgo_v2/config"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+
+	otlpStatusError = 2
+
+	otlpScopeName = "github.com/tonyjt/tgo_v2"
+)
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string `json:"timeUnixNano"`
+	Name         string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+func init() {
+	Register(BackendOtlp, otlpFactory)
+}
+
+//otlpFactory OTLP/HTTP json,CollectorEndpoint如 http://otel-collector:4318/v1/traces
+func otlpFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	endpoint := conf.CollectorEndpoint
+
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = strings.TrimRight(endpoint, "/") + "/v1/traces"
+	}
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = hostIp()
+	}
+
+	encode := func(spans []*zipkincore.Span) ([]byte, error) {
+		return otlpEncode(conf.ServiceName, hostName, spans)
+	}
+	collector := newBatchCollector(httpSender(endpoint, "application/json", encode))
+
+	return zipkinCollectorTracer(conf, hostPort, collector)
+}
+
+//otlpEncode encode v1 spans to otlp json
+func otlpEncode(serviceName string, hostName string, spans []*zipkincore.Span) ([]byte, error) {
+	scopeSpans := &otlpScopeSpans{}
+	scopeSpans.Scope.Name = otlpScopeName
+
+	for _, span := range spans {
+		info := spanInfoGet(span)
+
+		traceId := info.traceId
+		if len(traceId) < 32 {
+			//otlp trace id必须为16字节
+			traceId = strings.Repeat("0", 32-len(traceId)) + traceId
+		}
+
+		s := &otlpSpan{
+			TraceId:           traceId,
+			SpanId:            info.id,
+			ParentSpanId:      info.parentId,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(info.timestamp*1000, 10),
+			EndTimeUnixNano:   strconv.FormatInt((info.timestamp+info.duration)*1000, 10),
+		}
+		switch info.kind {
+		case "SERVER":
+			s.Kind = otlpSpanKindServer
+		case "CLIENT":
+			s.Kind = otlpSpanKindClient
+		}
+
+		for k, v := range info.tags {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+			if k == "error" && v == "true" {
+				s.Status.Code = otlpStatusError
+			}
+		}
+		if info.remote != nil && info.remote.Ipv4 != "" {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: "net.peer.ip", Value: otlpValue{StringValue: info.remote.Ipv4}})
+		}
+		for _, a := range info.annotations {
+			s.Events = append(s.Events, otlpEvent{TimeUnixNano: strconv.FormatInt(a.Timestamp*1000, 10), Name: a.Value})
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, s)
+	}
+
+	resourceSpans := &otlpResourceSpans{ScopeSpans: []*otlpScopeSpans{scopeSpans}}
+	resourceSpans.Resource.Attributes = []otlpAttribute{
+		{Key: "service.name", Value: otlpValue{StringValue: serviceName}},
+		{Key: "host.name", Value: otlpValue{StringValue: hostName}},
+	}
+	return json.Marshal(&otlpTraces{ResourceSpans: []*otlpResourceSpans{resourceSpans}})
+}
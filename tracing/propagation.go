@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go-opentracing"
+	"github.com/uber/jaeger-client-go"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderTraceparent = "traceparent"
+
+	headerB3TraceId = "x-b3-traceid"
+	headerB3SpanId  = "x-b3-spanid"
+	headerB3Sampled = "x-b3-sampled"
+)
+
+//Inject inject span context into http header,B3和W3C traceparent两种格式
+func Inject(span opentracing.Span, header http.Header) error {
+	err := span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+
+	if traceparent := traceparentGet(span); traceparent != "" {
+		header.Set(HeaderTraceparent, traceparent)
+	}
+	return err
+}
+
+//Extract extract span context from http header,优先B3,没有时使用W3C traceparent
+func Extract(header http.Header) (opentracing.SpanContext, error) {
+	tracer := opentracing.GlobalTracer()
+
+	wireContext, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+
+	if err == nil {
+		return wireContext, nil
+	}
+
+	traceparent := header.Get(HeaderTraceparent)
+	if traceparent == "" {
+		return nil, err
+	}
+
+	carrier, errParse := traceparentToB3(traceparent)
+	if errParse != nil {
+		return nil, errParse
+	}
+	//baggage等其他header保留
+	for k, v := range header {
+		if _, ok := carrier[strings.ToLower(k)]; !ok && len(v) > 0 {
+			carrier[k] = v[0]
+		}
+	}
+	return tracer.Extract(opentracing.TextMap, carrier)
+}
+
+//traceparentGet W3C traceparent of span,empty if backend not supported
+func traceparentGet(span opentracing.Span) string {
+	var (
+		traceId string
+		spanId  uint64
+		sampled bool
+	)
+
+	switch sc := span.Context().(type) {
+	case zipkintracer.SpanContext:
+		traceId, spanId, sampled = sc.TraceID.ToHex(), sc.SpanID, sc.Sampled
+	case jaeger.SpanContext:
+		traceId, spanId, sampled = sc.TraceID().String(), uint64(sc.SpanID()), sc.IsSampled()
+	default:
+		return ""
+	}
+
+	if len(traceId) < 32 {
+		traceId = strings.Repeat("0", 32-len(traceId)) + traceId
+	}
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%016x-%s", traceId, spanId, flags)
+}
+
+//traceparentToB3 version-traceid-spanid-flags转为B3 header
+func traceparentToB3(traceparent string) (opentracing.TextMapCarrier, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, errors.New("invalid traceparent:" + traceparent)
+	}
+	//version 00只有4段,更高版本允许后面追加字段
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, errors.New("invalid traceparent:" + traceparent)
+	}
+	for _, part := range parts[:4] {
+		if !hexIs(part) {
+			return nil, errors.New("invalid traceparent:" + traceparent)
+		}
+	}
+	if parts[0] == "ff" || parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return nil, errors.New("invalid traceparent:" + traceparent)
+	}
+
+	traceId := parts[1]
+	//64位trace id时去掉高位的0
+	if strings.HasPrefix(traceId, strings.Repeat("0", 16)) {
+		traceId = traceId[16:]
+	}
+
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	sampled := "0"
+	if flags&1 == 1 {
+		sampled = "1"
+	}
+
+	return opentracing.TextMapCarrier{
+		headerB3TraceId: traceId,
+		headerB3SpanId:  parts[2],
+		headerB3Sampled: sampled,
+	}, nil
+}
+
+//hexIs only lower case hex chars,W3C不允许大写
+func hexIs(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
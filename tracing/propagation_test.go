@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"testing"
+)
+
+func TestTraceparentToB3(t *testing.T) {
+	carrier, err := traceparentToB3("00-0000000000000000000000000000000a-00f067aa0ba902b7-01")
+
+	if err != nil {
+		t.Fatalf("parse error:%s", err.Error())
+	}
+	if carrier[headerB3TraceId] != "000000000000000a" || carrier[headerB3SpanId] != "00f067aa0ba902b7" || carrier[headerB3Sampled] != "1" {
+		t.Errorf("carrier:%+v", carrier)
+	}
+
+	carrier, err = traceparentToB3("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	if err != nil || carrier[headerB3TraceId] != "4bf92f3577b34da6a3ce929d0e0e4736" || carrier[headerB3Sampled] != "0" {
+		t.Errorf("carrier:%+v", carrier)
+	}
+
+	if _, err = traceparentToB3("00-00000000000000000000000000000000-00f067aa0ba902b7-01"); err == nil {
+		t.Errorf("zero trace id should be invalid")
+	}
+
+	invalids := []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, traceparent := range invalids {
+		if _, err = traceparentToB3(traceparent); err == nil {
+			t.Errorf("%s should be invalid", traceparent)
+		}
+	}
+
+	if _, err = traceparentToB3("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version with extra fields should be accepted:%s", err.Error())
+	}
+}
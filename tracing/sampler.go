@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/openzipkin/zipkin-go-opentracing"
+	"github.com/tonyjt/tgo_v2/config"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SamplerConst         = "const"
+	SamplerProbabilistic = "probabilistic"
+	SamplerRateLimiting  = "ratelimiting"
+)
+
+//zipkinSampler zipkintracer sampler by config,上游已有采样决定时不会调用
+func zipkinSampler(conf config.ZipkinSampler) zipkintracer.Sampler {
+	switch strings.ToLower(conf.Type) {
+	case SamplerProbabilistic:
+		return zipkintracer.NewBoundarySampler(conf.Param, rand.Int63())
+	case SamplerRateLimiting:
+		return rateLimitingSampler(conf.Param)
+	default:
+		on := conf.Param != 0
+		return func(id uint64) bool {
+			return on
+		}
+	}
+}
+
+//rateLimitingSampler 令牌桶,每秒最多采样perSecond个
+func rateLimitingSampler(perSecond float64) zipkintracer.Sampler {
+	var (
+		mux      sync.Mutex
+		balance  = perSecond
+		lastTick = time.Now()
+	)
+	if perSecond < 1 {
+		balance = 1
+	}
+
+	return func(id uint64) bool {
+		mux.Lock()
+		defer mux.Unlock()
+
+		now := time.Now()
+		balance += now.Sub(lastTick).Seconds() * perSecond
+		lastTick = now
+
+		max := perSecond
+		if max < 1 {
+			max = 1
+		}
+		if balance > max {
+			balance = max
+		}
+		if balance < 1 {
+			return false
+		}
+		balance--
+		return true
+	}
+}
+
+//SampleForced 路由在Routes中或请求的DebugHeader等于DebugSecret时强制采样
+func SampleForced(c *gin.Context) bool {
+	if !config.FeatureZipkin() {
+		return false
+	}
+	conf := config.ZipkinGet().Sampler
+
+	if conf.DebugHeader != "" && conf.DebugSecret != "" {
+		value := c.GetHeader(conf.DebugHeader)
+
+		if subtle.ConstantTimeCompare([]byte(value), []byte(conf.DebugSecret)) == 1 {
+			return true
+		}
+	}
+	path := c.Request.URL.Path
+	route := c.FullPath()
+
+	for _, r := range conf.Routes {
+		if r == path || (route != "" && r == route) {
+			return true
+		}
+	}
+	return false
+}
+
+//SampleForce force span to be sampled,忽略上游和采样器的决定
+func SampleForce(span opentracing.Span) {
+	ext.SamplingPriority.Set(span, 1)
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tonyjt/tgo_v2/config"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitingSampler(t *testing.T) {
+	sampler := rateLimitingSampler(2)
+
+	count := 0
+	for i := 0; i < 10; i++ {
+		if sampler(uint64(i)) {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("rate limiting sampled:%d", count)
+	}
+}
+
+func TestProbabilisticSampler(t *testing.T) {
+	cases := map[float64][2]int{0: {0, 0}, 1: {1000, 1000}, 0.5: {350, 650}}
+
+	for rate, expected := range cases {
+		sampler := zipkinSampler(config.ZipkinSampler{Type: SamplerProbabilistic, Param: rate})
+
+		count := 0
+		for i := 0; i < 1000; i++ {
+			if sampler(uint64(rand.Int63())) {
+				count++
+			}
+		}
+		if count < expected[0] || count > expected[1] {
+			t.Errorf("rate %v sampled:%d", rate, count)
+		}
+	}
+}
+
+func TestConstSampler(t *testing.T) {
+	if zipkinSampler(config.ZipkinSampler{Type: SamplerConst, Param: 0})(1) {
+		t.Error("const 0 should not sample")
+	}
+	if !zipkinSampler(config.ZipkinSampler{Param: 1})(1) {
+		t.Error("const 1 should sample")
+	}
+}
+
+func testSampleForced(conf config.ZipkinSampler, path string, header string) bool {
+	gin.SetMode(gin.TestMode)
+
+	sampler := &config.ZipkinGet().Sampler
+	before := *sampler
+	*sampler = conf
+	defer func() {
+		*sampler = before
+	}()
+
+	forced := false
+
+	engine := gin.New()
+	engine.GET("/users/:id", func(c *gin.Context) {
+		forced = SampleForced(c)
+	})
+	engine.GET("/health", func(c *gin.Context) {
+		forced = SampleForced(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if header != "" {
+		req.Header.Set("X-Trace-Debug", header)
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	return forced
+}
+
+func TestSampleForced_Routes(t *testing.T) {
+	conf := config.ZipkinSampler{Routes: []string{"/users/:id", "/health"}}
+
+	if !testSampleForced(conf, "/users/1", "") {
+		t.Error("route /users/:id should be forced")
+	}
+	if !testSampleForced(conf, "/health", "") {
+		t.Error("path /health should be forced")
+	}
+	if testSampleForced(config.ZipkinSampler{Routes: []string{"/orders"}}, "/users/1", "") {
+		t.Error("route not configured should not be forced")
+	}
+}
+
+func TestSampleForced_DebugHeader(t *testing.T) {
+	conf := config.ZipkinSampler{DebugHeader: "X-Trace-Debug", DebugSecret: "secret"}
+
+	if !testSampleForced(conf, "/users/1", "secret") {
+		t.Error("header with secret should be forced")
+	}
+	if testSampleForced(conf, "/users/1", "1") {
+		t.Error("header without secret should not be forced")
+	}
+	if testSampleForced(config.ZipkinSampler{DebugHeader: "X-Trace-Debug"}, "/users/1", "1") {
+		t.Error("header should be ignored when secret is empty")
+	}
+}
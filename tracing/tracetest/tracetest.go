@@ -0,0 +1,118 @@
+//Package tracetest in-memory tracer for asserting spans in tests
+package tracetest
+
+import (
+	"context"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"sync"
+	"testing"
+)
+
+var traceIdOnce sync.Once
+
+//Recorder in-memory tracer,记录所有finish的span
+type Recorder struct {
+	*mocktracer.MockTracer
+	prev opentracing.Tracer
+}
+
+//Install set in-memory tracer as global tracer,测试结束时调用Uninstall;dao的span需要feature配置Zipkin为true
+func Install() *Recorder {
+	traceIdOnce.Do(func() {
+		tracing.RegisterTraceId(traceId)
+	})
+
+	r := &Recorder{MockTracer: mocktracer.New(), prev: opentracing.GlobalTracer()}
+
+	opentracing.SetGlobalTracer(r.MockTracer)
+
+	return r
+}
+
+//Uninstall restore previous global tracer
+func (r *Recorder) Uninstall() {
+	opentracing.SetGlobalTracer(r.prev)
+}
+
+//Spans finished spans with name
+func (r *Recorder) Spans(name string) []*mocktracer.MockSpan {
+	var spans []*mocktracer.MockSpan
+
+	for _, span := range r.FinishedSpans() {
+		if span.OperationName == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+//Names names of finished spans,按finish顺序
+func (r *Recorder) Names() []string {
+	var names []string
+
+	for _, span := range r.FinishedSpans() {
+		names = append(names, span.OperationName)
+	}
+	return names
+}
+
+//AssertSpan assert span with name finished and has tags,返回最后一个同名的span
+func (r *Recorder) AssertSpan(t testing.TB, name string, tags map[string]interface{}) *mocktracer.MockSpan {
+	t.Helper()
+
+	spans := r.Spans(name)
+
+	if len(spans) == 0 {
+		t.Errorf("span %s not found,finished spans:%v", name, r.Names())
+		return nil
+	}
+	span := spans[len(spans)-1]
+
+	for k, v := range tags {
+		if tag := span.Tag(k); fmt.Sprint(tag) != fmt.Sprint(v) {
+			t.Errorf("span %s tag %s:%v,expected:%v", name, k, tag, v)
+		}
+	}
+	return span
+}
+
+//AssertError assert span with name has error flag or not
+func (r *Recorder) AssertError(t testing.TB, name string, isError bool) *mocktracer.MockSpan {
+	t.Helper()
+
+	span := r.AssertSpan(t, name, nil)
+
+	if span == nil {
+		return nil
+	}
+	flag, _ := span.Tag(string(ext.Error)).(bool)
+
+	if flag != isError {
+		t.Errorf("span %s error flag:%t,expected:%t", name, flag, isError)
+	}
+	return span
+}
+
+//AssertChild assert child span's parent is parent span
+func AssertChild(t testing.TB, parent *mocktracer.MockSpan, child *mocktracer.MockSpan) {
+	t.Helper()
+
+	if parent == nil || child == nil {
+		t.Errorf("parent or child span is nil")
+		return
+	}
+	if child.ParentID != parent.SpanContext.SpanID || child.SpanContext.TraceID != parent.SpanContext.TraceID {
+		t.Errorf("span %s is not child of %s", child.OperationName, parent.OperationName)
+	}
+}
+
+func traceId(ctx context.Context, span opentracing.Span) string {
+	if sc, ok := span.Context().(mocktracer.MockSpanContext); ok {
+		return fmt.Sprintf("%d", sc.TraceID)
+	}
+	return ""
+}
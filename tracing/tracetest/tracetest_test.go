@@ -0,0 +1,41 @@
+package tracetest
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/tonyjt/tgo_v2/tracing"
+	"os"
+	"testing"
+)
+
+//TestMain 在项目根目录运行,使用根目录的configs
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRecorder(t *testing.T) {
+	r := Install()
+	defer r.Uninstall()
+
+	parent, ctx := opentracing.StartSpanFromContext(context.Background(), "parent")
+
+	child, _ := opentracing.StartSpanFromContext(ctx, "child")
+	child.SetTag("db", "tgo1")
+	ext.Error.Set(child, true)
+	child.Finish()
+	parent.Finish()
+
+	c := r.AssertSpan(t, "child", map[string]interface{}{"db": "tgo1"})
+	r.AssertError(t, "child", true)
+	p := r.AssertError(t, "parent", false)
+
+	AssertChild(t, p, c)
+
+	if tracing.TraceId(ctx) == "" {
+		t.Errorf("trace id is empty")
+	}
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/log"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	BackendZipkin   = "zipkin"
+	BackendZipkinV2 = "zipkin-v2"
+	BackendJaeger   = "jaeger"
+	BackendOtlp     = "otlp"
+	BackendStdout   = "stdout"
+	BackendFile     = "file"
+	BackendNoop     = "noop"
+)
+
+//Factory create tracer by config,hostPort is the address of current service
+type Factory func(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error)
+
+//TraceIdExtractor get trace id of span,empty if span is not created by the backend
+type TraceIdExtractor func(ctx context.Context, span opentracing.Span) string
+
+var (
+	factories  = make(map[string]Factory)
+	extractors []TraceIdExtractor
+	closer     io.Closer
+	mux        sync.RWMutex
+)
+
+func init() {
+	Register(BackendNoop, noopFactory)
+}
+
+//Register register backend factory,replace if exists
+func Register(backend string, factory Factory) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	factories[strings.ToLower(backend)] = factory
+}
+
+//RegisterTraceId register trace id extractor of backend
+func RegisterTraceId(extractor TraceIdExtractor) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	extractors = append(extractors, extractor)
+}
+
+//Load create tracer by config Backend and set it as global tracer,需要放在middleware.Register之前
+func Load(hostPort string) {
+	if !config.FeatureZipkin() {
+		panic("zipkin feature is false")
+	}
+	conf := config.ZipkinGet()
+
+	backend := strings.ToLower(conf.Backend)
+	if backend == "" {
+		backend = BackendZipkin
+	}
+
+	mux.RLock()
+	factory, ok := factories[backend]
+	mux.RUnlock()
+
+	if !ok {
+		panic(fmt.Sprintf("tracing backend %s not registered", backend))
+	}
+
+	tracer, c, err := factory(conf, fmt.Sprintf("%s%s", hostIp(), hostPort))
+
+	if err != nil {
+		log.Errorf("unable to create %s tracer: %+v", backend, err)
+		panic(err)
+	}
+
+	mux.Lock()
+	closer = c
+	mux.Unlock()
+
+	opentracing.SetGlobalTracer(tracer)
+}
+
+//Close flush and close tracer
+func Close() error {
+	mux.Lock()
+	defer mux.Unlock()
+
+	if closer == nil {
+		return nil
+	}
+	err := closer.Close()
+	closer = nil
+	return err
+}
+
+//TraceId trace id of span in ctx,empty if not exists
+func TraceId(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+
+	if span == nil {
+		return ""
+	}
+
+	mux.RLock()
+	defer mux.RUnlock()
+
+	for _, extractor := range extractors {
+		if id := extractor(ctx, span); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+//hostIp ipv4 of current host
+func hostIp() string {
+	var host string
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		panic(err)
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+			if ipnet.IP.To4() != nil {
+				host = ipnet.IP.String()
+			}
+		}
+	}
+	return host
+}
+
+func noopFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	return opentracing.NoopTracer{}, nil, nil
+}
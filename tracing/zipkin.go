@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/opentracing/opentracing-go"
+	"github.com/openzipkin/zipkin-go-opentracing"
+	"github.com/tonyjt/tgo_v2/config"
+	"io"
+	"os"
+)
+
+func init() {
+	Register(BackendZipkin, zipkinFactory)
+	Register(BackendZipkinV2, zipkinV2Factory)
+	Register(BackendStdout, stdoutFactory)
+	Register(BackendFile, fileFactory)
+
+	RegisterTraceId(zipkinTraceId)
+}
+
+//closerFunc io.Closer
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+//zipkinTracer create zipkintracer with collector,zipkin,zipkin-v2,stdout,file,otlp共用
+func zipkinTracer(conf *config.ConfigZipkin, hostPort string, collector zipkintracer.Collector) (opentracing.Tracer, error) {
+	recorder := zipkintracer.NewRecorder(collector, conf.Debug, hostPort, conf.ServiceName)
+
+	return zipkintracer.NewTracer(
+		recorder,
+		zipkintracer.ClientServerSameSpan(conf.SameSpan),
+		zipkintracer.TraceID128Bit(conf.TraceID128Bit),
+		zipkintracer.WithSampler(zipkinSampler(conf.Sampler)),
+	)
+}
+
+func zipkinCollectorTracer(conf *config.ConfigZipkin, hostPort string, collector zipkintracer.Collector) (opentracing.Tracer, io.Closer, error) {
+	tracer, err := zipkinTracer(conf, hostPort, collector)
+
+	if err != nil {
+		collector.Close()
+		return nil, nil, err
+	}
+	return tracer, collector, nil
+}
+
+func zipkinFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	collector, err := zipkintracer.NewHTTPCollector(conf.CollectorEndpoint)
+
+	if err != nil {
+		return nil, nil, err
+	}
+	return zipkinCollectorTracer(conf, hostPort, collector)
+}
+
+func zipkinV2Factory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	collector := newBatchCollector(httpSender(conf.CollectorEndpoint, "application/json", zipkinV2Encode))
+
+	return zipkinCollectorTracer(conf, hostPort, collector)
+}
+
+func stdoutFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	collector := newBatchCollector(writerSender(os.Stdout))
+
+	return zipkinCollectorTracer(conf, hostPort, collector)
+}
+
+func fileFactory(conf *config.ConfigZipkin, hostPort string) (opentracing.Tracer, io.Closer, error) {
+	if conf.File == "" {
+		return nil, nil, errors.New("tracing file is empty")
+	}
+	file, err := os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	if err != nil {
+		return nil, nil, err
+	}
+	collector := newBatchCollector(writerSender(file))
+
+	tracer, err := zipkinTracer(conf, hostPort, collector)
+
+	if err != nil {
+		collector.Close()
+		file.Close()
+		return nil, nil, err
+	}
+	return tracer, closerFunc(func() error {
+		err := collector.Close()
+		if errFile := file.Close(); err == nil {
+			err = errFile
+		}
+		return err
+	}), nil
+}
+
+func zipkinTraceId(ctx context.Context, span opentracing.Span) string {
+	if sc, ok := span.Context().(zipkintracer.SpanContext); ok {
+		return sc.TraceID.ToHex()
+	}
+	return ""
+}
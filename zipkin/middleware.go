@@ -1,44 +1,227 @@
 package zipkin
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/grpc-ecosystem/grpc-opentracing/go/otgrpc"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	"github.com/tonyjt/tgo_v2/config"
+	"github.com/tonyjt/tgo_v2/pconst"
+	"github.com/tonyjt/tgo_v2/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	spanNameNotFound = "not_found"
+
+	headerRequestId = "X-Request-Id"
 )
 
 func MiddlewareHttp() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 
+		if pathExcluded(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
 		tracer := opentracing.GlobalTracer()
 
-		wireContext, err := tracer.Extract(
-			opentracing.TextMap,
-			opentracing.HTTPHeadersCarrier(c.Request.Header),
-		)
+		wireContext, err := tracing.Extract(c.Request.Header)
+
+		//使用路由模板命名,避免/user/123这种高基数的名称
+		on := c.FullPath()
+		if on == "" {
+			on = spanNameNotFound
+		}
 
 		var span opentracing.Span
-		on := c.Request.URL.Path
 		if err != nil {
-			span = opentracing.StartSpan(on)
+			span = tracer.StartSpan(on, ext.SpanKindRPCServer)
 		} else {
 			span = tracer.StartSpan(on, ext.RPCServerOption(wireContext))
 		}
 
-		span.SetTag("server-http", "here")
+		ext.HTTPMethod.Set(span, c.Request.Method)
+		ext.HTTPUrl.Set(span, c.Request.URL.Path)
+		span.SetTag("http.client_ip", c.ClientIP())
+
+		if tracing.SampleForced(c) {
+			tracing.SampleForce(span)
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				ext.Error.Set(span, true)
+				ext.HTTPStatusCode.Set(span, http.StatusInternalServerError)
+				span.LogKV("event", "panic", "message", fmt.Sprint(r), "stack", string(debug.Stack()))
+				span.Finish()
+				//交给gin的recovery处理
+				panic(r)
+			}
+		}()
 
-		defer span.Finish()
 		ctx := opentracing.ContextWithSpan(c.Request.Context(), span)
 
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
+
+		spanFinishHttp(c, span)
 	}
 }
 
+//spanFinishHttp set response tags and finish span
+func spanFinishHttp(c *gin.Context, span opentracing.Span) {
+	status := c.Writer.Status()
+	ext.HTTPStatusCode.Set(span, uint16(status))
+
+	isError := status >= http.StatusInternalServerError
+
+	if code, ok := c.Get(pconst.CONTEXT_KEY_CODE); ok {
+		span.SetTag("code", code)
+	}
+	if result, ok := c.Get(pconst.CONTEXT_KEY_RESULT); ok {
+		if r, ok := result.(bool); ok && !r {
+			isError = true
+		}
+	}
+	if len(c.Errors) > 0 {
+		isError = true
+		span.LogKV("event", "error", "message", c.Errors.String())
+	}
+	if isError {
+		ext.Error.Set(span, true)
+	}
+
+	span.Finish()
+}
+
+//pathExcluded path in config ExcludePaths
+func pathExcluded(path string) bool {
+	if !config.FeatureZipkin() {
+		return false
+	}
+	for _, p := range config.ZipkinGet().ExcludePaths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+//MiddlewareBaggage put W3C baggage header and request id into ctx,放在MiddlewareHttp之后,
+//上游不可信时(见config.ZipkinBaggage)不提取,并去掉tracer header中的user id等
+func MiddlewareBaggage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		if baggageTrusted(c.Request.RemoteAddr) {
+			ctx = tracing.ExtractBaggage(ctx, c.Request.Header)
+
+			if id := c.GetHeader(headerRequestId); id != "" && tracing.RequestId(ctx) == "" {
+				ctx = tracing.SetRequestId(ctx, id)
+			}
+		} else {
+			//zipkin,jaeger的header(ot-baggage-*,uberctx-*)也会带上baggage
+			ctx = tracing.DropBaggage(ctx, tracing.BaggageUserId, tracing.BaggageTenantId, tracing.BaggageRequestId)
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+//baggageTrusted remote addr is trusted by config Baggage
+func baggageTrusted(remoteAddr string) bool {
+	if !config.FeatureZipkin() {
+		return false
+	}
+	conf := config.ZipkinGet().Baggage
+
+	if conf.Trusted {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, p := range conf.Proxies {
+		if strings.Contains(p, "/") {
+			if _, ipNet, err := net.ParseCIDR(p); err == nil && ipNet.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+//BaggageSet set baggage item to request ctx,如在登录验证后设置user id
+func BaggageSet(c *gin.Context, key string, value string) {
+	c.Request = c.Request.WithContext(tracing.SetBaggage(c.Request.Context(), key, value))
+}
+
 func MiddlewareGrpc() grpc.UnaryServerInterceptor {
-	return otgrpc.OpenTracingServerInterceptor(opentracing.GlobalTracer(), otgrpc.LogPayloads())
+	interceptor := otgrpc.OpenTracingServerInterceptor(opentracing.GlobalTracer(), otgrpc.LogPayloads())
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return handler(baggageGrpc(ctx), req)
+		})
+	}
+}
+
+//MiddlewareGrpcStream stream server interceptor,grpc.StreamInterceptor(zipkin.MiddlewareGrpcStream())
+func MiddlewareGrpcStream() grpc.StreamServerInterceptor {
+	interceptor := otgrpc.OpenTracingStreamServerInterceptor(opentracing.GlobalTracer(), otgrpc.LogPayloads())
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return interceptor(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+			return handler(srv, &baggageServerStream{ServerStream: ss, ctx: baggageGrpc(ss.Context())})
+		})
+	}
+}
+
+//baggageGrpc 上游可信时提取metadata中的baggage,否则同MiddlewareBaggage去掉tracer metadata带来的user id等
+func baggageGrpc(ctx context.Context) context.Context {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+
+	if baggageTrusted(remoteAddr) {
+		return tracing.ExtractBaggageGrpc(ctx)
+	}
+	return tracing.DropBaggage(ctx, tracing.BaggageUserId, tracing.BaggageTenantId, tracing.BaggageRequestId)
+}
+
+//baggageServerStream ServerStream with baggage in ctx
+type baggageServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
 
+func (s *baggageServerStream) Context() context.Context {
+	return s.ctx
 }
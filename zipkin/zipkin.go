@@ -1,54 +1,21 @@
 package zipkin
 
 import (
-	"fmt"
-	"github.com/opentracing/opentracing-go"
-	"github.com/openzipkin/zipkin-go-opentracing"
-	"github.com/tonyjt/tgo_v2/config"
-	"github.com/tonyjt/tgo_v2/log"
-	"net"
+	"context"
+	"github.com/tonyjt/tgo_v2/tracing"
 )
 
-//Load grpc中load，需要放在middleware.Register之前
+//Load grpc中load，需要放在middleware.Register之前,backend由zipkin配置的Backend决定
 func Load(hostPort string) {
-	if !config.FeatureZipkin() {
-		panic("zipkin feature is false")
-	}
-	config := config.ZipkinGet()
-
-	collector, err := zipkintracer.NewHTTPCollector(config.CollectorEndpoint)
-
-	if err != nil {
-		log.Errorf("unable to create zipkin http collector : %+v", err)
-
-		panic(err)
-	}
-
-	var host string
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		panic(err)
-	}
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				host = ipnet.IP.String()
-			}
-		}
-	}
-
-	recorder := zipkintracer.NewRecorder(collector, config.Debug, fmt.Sprintf("%s%s", host, hostPort), config.ServiceName)
-
-	tracer, err := zipkintracer.NewTracer(
-		recorder,
-		zipkintracer.ClientServerSameSpan(config.SameSpan),
-		zipkintracer.TraceID128Bit(config.TraceID128Bit),
-	)
+	tracing.Load(hostPort)
+}
 
-	if err != nil {
-		log.Errorf("unable to create Zipkin tracer: %+v", err)
-		panic(err)
-	}
+//Close flush and close tracer
+func Close() error {
+	return tracing.Close()
+}
 
-	opentracing.InitGlobalTracer(tracer)
+//TraceId trace id of span in ctx,empty if not exists
+func TraceId(ctx context.Context) string {
+	return tracing.TraceId(ctx)
 }